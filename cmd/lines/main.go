@@ -0,0 +1,104 @@
+//Command lines unwraps lines of a file or stdin to stdout or to a file, see lines.Unwrap.
+//With -o naming the input file the file is unwrapped in place, as with -w
+//Usage: lines [-c connector] [-strict] [-w | -o file] [file]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/velmascooby/tools/files/lines"
+)
+
+//quietLogger drops messages of the lines package, errors are reported by main
+type quietLogger struct{}
+
+func (quietLogger) Infof(format string, args ...interface{})    {}
+func (quietLogger) Warningf(format string, args ...interface{}) {}
+
+//flags are the command line flags of the command
+type flags struct {
+	connector string
+	strict    bool
+	inPlace   bool
+	output    string
+}
+
+func main() {
+	var f flags
+	flag.StringVar(&f.connector, "c", "\\", "connector joining a line with the next one")
+	flag.BoolVar(&f.strict, "strict", false, "fail on a dangling connector and on invalid UTF-8")
+	flag.BoolVar(&f.inPlace, "w", false, "write the result to the file instead of stdout")
+	flag.StringVar(&f.output, "o", "", "file to write the result to instead of stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: lines [-c connector] [-strict] [-w | -o file] [file]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(flag.Args(), f, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "lines: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, f flags, stdin io.Reader, stdout io.Writer) error {
+	if len(args) > 1 {
+		return errors.New("At most one file can be unwrapped")
+	}
+	if f.connector == "" {
+		return errors.New("Connector must not be empty")
+	}
+	if f.inPlace && f.output != "" {
+		return errors.New("-w and -o can't be used together")
+	}
+
+	opts := lines.Options{
+		Connectors:              []lines.ConnectorRule{{Connector: f.connector, IgnoreInComments: true}},
+		FailOnDanglingConnector: f.strict,
+		ValidateUTF8:            f.strict,
+		Logger:                  quietLogger{},
+	}
+
+	if len(args) == 0 {
+		if f.inPlace {
+			return errors.New("-w needs a file")
+		}
+		if f.output != "" {
+			return unwrapToOutput(stdin, f.output, opts)
+		}
+		return lines.UnwrapStreamWithOptions(stdin, stdout, opts)
+	}
+
+	if f.inPlace {
+		return lines.UnwrapInPlaceWithOptions(args[0], opts)
+	}
+	if f.output != "" {
+		//also unwraps in place when output is the file
+		return lines.UnwrapToFileWithOptions(args[0], f.output, true, opts)
+	}
+
+	in, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return lines.UnwrapStreamWithOptions(in, stdout, opts)
+}
+
+//unwrapToOutput unwraps stdin to the file output, created or truncated
+func unwrapToOutput(stdin io.Reader, output string, opts lines.Options) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	err = lines.UnwrapStreamWithOptions(stdin, out, opts)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string //content of the file argument, none when ""
+		stdin   string
+		f       flags
+		stdout  string
+		written string //content of the file -w or -o writes
+		wantErr string
+	}{
+		{name: "stdin", stdin: "a \\\nb\n", f: flags{connector: "\\"}, stdout: "a b\n\n"},
+		{name: "file", file: "a \\\nb\n", f: flags{connector: "\\"}, stdout: "a b\n\n"},
+		{name: "connector", file: "a &&\nb\n", f: flags{connector: "&&"}, stdout: "a b\n\n"},
+		{name: "strict dangling", file: "a \\\n", f: flags{connector: "\\", strict: true}, wantErr: "dangling"},
+		{name: "in place", file: "a \\\nb\n", f: flags{connector: "\\", inPlace: true}, written: "a b\n\n"},
+		{name: "output", file: "a \\\nb\n", f: flags{connector: "\\", output: "out.txt"}, written: "a b\n\n"},
+		{name: "output from stdin", stdin: "a \\\nb\n", f: flags{connector: "\\", output: "out.txt"}, written: "a b\n\n"},
+		{name: "output is input", file: "a \\\nb\n", f: flags{connector: "\\", output: "in.txt"}, written: "a b\n\n"},
+		{name: "in place and output", file: "a\n", f: flags{connector: "\\", inPlace: true, output: "out.txt"}, wantErr: "-w and -o"},
+		{name: "in place without file", stdin: "a\n", f: flags{connector: "\\", inPlace: true}, wantErr: "-w needs a file"},
+		{name: "empty connector", stdin: "a\n", wantErr: "Connector must not be empty"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "in.txt")
+			var args []string
+			if test.file != "" {
+				if err := ioutil.WriteFile(input, []byte(test.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+				args = []string{input}
+			}
+			if test.f.output != "" {
+				test.f.output = filepath.Join(dir, test.f.output)
+			}
+
+			var stdout bytes.Buffer
+			err := run(args, test.f, strings.NewReader(test.stdin), &stdout)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got %v, want an error with %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != test.stdout {
+				t.Errorf("stdout %q, want %q", stdout.String(), test.stdout)
+			}
+
+			if test.written == "" {
+				return
+			}
+			written := input
+			if test.f.output != "" {
+				written = test.f.output
+			}
+			text, err := ioutil.ReadFile(written)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(text) != test.written {
+				t.Errorf("wrote %q, want %q", text, test.written)
+			}
+		})
+	}
+}
+
+func TestRunTooManyFiles(t *testing.T) {
+	if err := run([]string{"a", "b"}, flags{connector: "\\"}, strings.NewReader(""), ioutil.Discard); err == nil {
+		t.Error("got no error for two files")
+	}
+}
+
+func TestRunOutputKeptOnError(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	output := filepath.Join(dir, "out.txt")
+	if err := ioutil.WriteFile(input, []byte("a \\\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(output, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{input}, flags{connector: "\\", strict: true, output: output}, strings.NewReader(""), ioutil.Discard); err == nil {
+		t.Fatal("got no error for a dangling connector")
+	}
+	if text, err := ioutil.ReadFile(output); err != nil || string(text) != "old\n" {
+		t.Errorf("output is %q, %v, want it left as it was", text, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("dir has %d entries, %v, want no temp file left", len(entries), err)
+	}
+}
+
+func TestRunStrict(t *testing.T) {
+	tests := []struct {
+		name   string
+		stdin  string
+		strict bool
+		fails  bool
+	}{
+		{"invalid UTF-8", "a \\\n\xff\n", false, false},
+		{"invalid UTF-8, strict", "a \\\n\xff\n", true, true},
+		{"dangling", "a \\\n", false, false},
+		{"dangling, strict", "a \\\n", true, true},
+		{"clean, strict", "a \\\nb\n", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := run(nil, flags{connector: "\\", strict: test.strict}, strings.NewReader(test.stdin), ioutil.Discard)
+			if (err != nil) != test.fails {
+				t.Errorf("got %v, want an error: %t", err, test.fails)
+			}
+		})
+	}
+}
+
+//TestMain runs main when the test binary is started by TestExitCode
+func TestMain(m *testing.M) {
+	if args := os.Getenv("LINES_TEST_MAIN"); args != "" {
+		os.Args = append([]string{"lines"}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"unwrapped", "-c &&", "a &&\nb\n", 0, "a b\n\n", ""},
+		{"dangling, strict", "-strict", "a \\\n", 1, "", "lines: "},
+		{"two files", "a b", "", 1, "", "lines: At most one file can be unwrapped\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0])
+			cmd.Env = append(os.Environ(), "LINES_TEST_MAIN="+test.args)
+			cmd.Stdin = strings.NewReader(test.stdin)
+			var stdout, stderr bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+			err := cmd.Run()
+			code := 0
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != test.code {
+				t.Errorf("exit code %d, want %d", code, test.code)
+			}
+			if stdout.String() != test.stdout {
+				t.Errorf("stdout %q, want %q", stdout.String(), test.stdout)
+			}
+			//messages of the lines package are dropped, only the error is printed
+			if !strings.HasPrefix(stderr.String(), test.stderr) || (stderr.Len() == 0) != (test.stderr == "") || strings.Count(stderr.String(), "\n") > 1 {
+				t.Errorf("stderr %q, want at most one line starting with %q", stderr.String(), test.stderr)
+			}
+		})
+	}
+}
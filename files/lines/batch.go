@@ -0,0 +1,265 @@
+package lines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+//ErrTooManyFiles is returned by batch functions when more files match than Options.MaxFiles
+var ErrTooManyFiles = errors.New("too many files")
+
+//ErrorStrategy tells batch functions what to do when unwrapping a file fails
+type ErrorStrategy int
+
+const (
+	//CollectErrors unwraps all files and returns errors of all failed ones in a BatchError
+	CollectErrors ErrorStrategy = iota
+	//StopOnError stops at the first failed file and returns its error
+	StopOnError
+	//CollectErrorsUpTo works as CollectErrors but stops once Options.MaxErrors files failed
+	CollectErrorsUpTo
+)
+
+//BatchError holds errors of all files that failed in a batch
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+//Unwrap returns errors of all failed files, errors.Is and errors.As look through all of them
+func (e *BatchError) Unwrap() []error {
+	return e.Errors
+}
+
+//Is tells if an error of any failed file is target, errors.Is only unwraps
+//a slice of errors since Go 1.20
+func (e *BatchError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+//As finds the first error of a failed file matching target, see Is
+func (e *BatchError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+//UnwrapFiles unwraps every file in filePaths to its own temp file, see UnwrapWithOptions.
+//Returns: map from source path to temp file path of every unwrapped file
+//				 function to clean up all temp files, also when err is not nil
+//				 error if something went wrong, what is returned depends on opts.ErrorStrategy
+func UnwrapFiles(filePaths []string, opts Options) (results map[string]string, cleanUp func(), err error) {
+	return unwrapFiles(context.Background(), filePaths, opts)
+}
+
+//unwrapFiles works as UnwrapFiles and stops with ctx.Err() once ctx is done
+func unwrapFiles(ctx context.Context, filePaths []string, opts Options) (results map[string]string, cleanUp func(), err error) {
+
+	results = map[string]string{}
+
+	if opts.MaxFiles > 0 && len(filePaths) > opts.MaxFiles {
+		err := fmt.Errorf("%w: %d files, at most %d allowed", ErrTooManyFiles, len(filePaths), opts.MaxFiles)
+		opts.logger().Warningf(err.Error())
+		return results, func() {}, err
+	}
+
+	var cleanUps []func()
+	cleanUp = func() {
+		for _, cleanUp := range cleanUps {
+			cleanUp()
+		}
+	}
+
+	var errs []error
+	for n, filePath := range filePaths {
+		if err := ctx.Err(); err != nil {
+			return results, cleanUp, err
+		}
+		newFilePath, fileCleanUp, err := unwrapFileWithTimeout(ctx, filePath, opts)
+		cleanUps = append(cleanUps, ignoreError(fileCleanUp))
+		if opts.Progress != nil {
+			opts.Progress(n+1, len(filePaths))
+		}
+		if err == nil {
+			results[filePath] = newFilePath
+			continue
+		}
+
+		err = fmt.Errorf("Failed to unwrap file: %s: %w", filePath, err)
+		if opts.ErrorStrategy == StopOnError {
+			return results, cleanUp, err
+		}
+		errs = append(errs, err)
+		if opts.ErrorStrategy == CollectErrorsUpTo && len(errs) >= opts.MaxErrors {
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return results, cleanUp, &BatchError{Errors: errs}
+	}
+	return results, cleanUp, nil
+}
+
+//unwrapFileWithTimeout unwraps filePath as UnwrapWithOptions does within Options.PerFileTimeout
+func unwrapFileWithTimeout(ctx context.Context, filePath string, opts Options) (newFilePath string, cleanUp func() error, err error) {
+	if opts.PerFileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.PerFileTimeout)
+		defer cancel()
+	}
+	return unwrapFile(ctx, filePath, opts, nil)
+}
+
+//UnwrapDir unwraps every file under root whose name matches pattern (see filepath.Match)
+//as UnwrapFiles does. Symlinks are not followed. A path that can't be walked is skipped and
+//its error returned in the BatchError with errors of failed files, with StopOnError it stops
+//the walk before anything is unwrapped
+func UnwrapDir(root string, pattern string, opts Options) (results map[string]string, cleanUp func(), err error) {
+	return UnwrapDirContext(context.Background(), root, pattern, opts)
+}
+
+//UnwrapDirContext works as UnwrapDir and stops with ctx.Err() as soon as ctx is done, while
+//walking, between files or while reading a file. Temp files of files unwrapped until then
+//are removed by cleanUp as usual
+func UnwrapDirContext(ctx context.Context, root string, pattern string, opts Options) (results map[string]string, cleanUp func(), err error) {
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		err = fmt.Errorf("Bad file name pattern: %s: %w", pattern, err)
+		opts.logger().Warningf(err.Error())
+		return map[string]string{}, func() {}, err
+	}
+
+	filePaths, walkErrs, err := opts.walkFiles(ctx, root, func(path string) bool {
+		matched, _ := filepath.Match(pattern, filepath.Base(path))
+		return matched
+	}, opts.ErrorStrategy != StopOnError)
+	if err != nil {
+		return map[string]string{}, func() {}, err
+	}
+
+	results, cleanUp, err = unwrapFiles(ctx, filePaths, opts)
+	return results, cleanUp, withWalkErrors(err, walkErrs)
+}
+
+//UnwrapGlob unwraps every file matching pattern as UnwrapFiles does. pattern is matched as
+//filepath.Match does, with "**" as a whole path element matching any number of directories,
+//e.g. "templates/**/*.tmpl". Files are searched for under the directories before the first
+//element with a wildcard, symlinks are not followed. Walk errors are returned as UnwrapDir does
+func UnwrapGlob(pattern string, opts Options) (results map[string]string, cleanUp func(), err error) {
+
+	elems := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	for _, elem := range elems {
+		if _, err := filepath.Match(elem, ""); err != nil {
+			err = fmt.Errorf("Bad file name pattern: %s: %w", pattern, err)
+			opts.logger().Warningf(err.Error())
+			return map[string]string{}, func() {}, err
+		}
+	}
+
+	static := 0
+	for static < len(elems)-1 && !hasMeta(elems[static]) {
+		static++
+	}
+	root := filepath.FromSlash(strings.Join(elems[:static], "/"))
+	if static == 0 {
+		root = "."
+	} else if root == "" {
+		root = string(filepath.Separator) //absolute pattern
+	}
+
+	filePaths, walkErrs, err := opts.walkFiles(context.Background(), root, func(path string) bool {
+		return matchGlob(elems, strings.Split(filepath.ToSlash(filepath.Clean(path)), "/"))
+	}, opts.ErrorStrategy != StopOnError)
+	if err != nil {
+		return map[string]string{}, func() {}, err
+	}
+
+	results, cleanUp, err = unwrapFiles(context.Background(), filePaths, opts)
+	return results, cleanUp, withWalkErrors(err, walkErrs)
+}
+
+//withWalkErrors adds walkErrs to the BatchError err of unwrapping walked files,
+//any other error is returned as it is
+func withWalkErrors(err error, walkErrs []error) error {
+	if len(walkErrs) == 0 {
+		return err
+	}
+	if batchErr, ok := err.(*BatchError); ok {
+		walkErrs = append(walkErrs, batchErr.Errors...)
+	} else if err != nil {
+		return err
+	}
+	return &BatchError{Errors: walkErrs}
+}
+
+//hasMeta tells if a path element of a pattern has a wildcard
+func hasMeta(elem string) bool {
+	return strings.ContainsAny(elem, "*?[\\")
+}
+
+//matchGlob matches path elements against pattern elements, see UnwrapGlob
+func matchGlob(pattern []string, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchGlob(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+//walkFiles returns paths of regular files under root accepted by match, symlinks are not followed.
+//With keepGoing a path that can't be walked is skipped and its error added to errs,
+//otherwise it stops the walk with err. The walk stops with ctx.Err() once ctx is done
+func (opts Options) walkFiles(ctx context.Context, root string, match func(path string) bool, keepGoing bool) (filePaths []string, errs []error, err error) {
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			err = fmt.Errorf("Failed to walk: %s: %w", path, err)
+			opts.logger().Warningf(err.Error())
+			if keepGoing {
+				errs = append(errs, err)
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() && match(path) {
+			filePaths = append(filePaths, path)
+		}
+		return nil
+	})
+	return filePaths, errs, err
+}
@@ -0,0 +1,226 @@
+package lines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestErrorStrategy(t *testing.T) {
+	dir := t.TempDir()
+	good := writeTemp(t, "good.txt", "a \\\nb\n")
+	filePaths := []string{
+		filepath.Join(dir, "missing1.txt"),
+		good,
+		filepath.Join(dir, "missing2.txt"),
+		filepath.Join(dir, "missing3.txt"),
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		errs    int //errors in the BatchError, 0 for a single error
+		unwraps int
+	}{
+		{"collect all", Options{ErrorStrategy: CollectErrors}, 3, 1},
+		{"stop on first", Options{ErrorStrategy: StopOnError}, 0, 0},
+		{"collect up to", Options{ErrorStrategy: CollectErrorsUpTo, MaxErrors: 2}, 2, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.TempDir = t.TempDir()
+			results, cleanUp, err := UnwrapFiles(filePaths, test.opts)
+			defer cleanUp()
+
+			if !errors.Is(err, ErrOpen) {
+				t.Fatalf("got %v, want an open error", err)
+			}
+			var batchErr *BatchError
+			if errors.As(err, &batchErr) != (test.errs > 0) {
+				t.Fatalf("got %T, want a BatchError: %t", err, test.errs > 0)
+			}
+			if batchErr != nil && len(batchErr.Errors) != test.errs {
+				t.Errorf("got %d errors, want %d", len(batchErr.Errors), test.errs)
+			}
+			if len(results) != test.unwraps {
+				t.Errorf("unwrapped %v, want %d files", results, test.unwraps)
+			}
+		})
+	}
+}
+
+func TestBatchErrorIsAs(t *testing.T) {
+	lineErr := &LineError{Line: 3, Err: ErrDanglingConnector}
+	batchErr := &BatchError{Errors: []error{
+		fmt.Errorf("%w: a.txt", ErrOpen),
+		fmt.Errorf("b.txt: %w", lineErr),
+	}}
+	tests := []struct {
+		name   string
+		target error
+		want   bool
+	}{
+		{"first", ErrOpen, true},
+		{"wrapped in second", ErrDanglingConnector, true},
+		{"missing", ErrBinaryInput, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			//the methods are called directly, errors.Is unwraps a slice of errors by itself since Go 1.20
+			if got := batchErr.Is(test.target); got != test.want {
+				t.Errorf("Is(%v) = %t, want %t", test.target, got, test.want)
+			}
+			if got := errors.Is(batchErr, test.target); got != test.want {
+				t.Errorf("errors.Is(%v) = %t, want %t", test.target, got, test.want)
+			}
+		})
+	}
+
+	var found *LineError
+	if !batchErr.As(&found) || found != lineErr {
+		t.Errorf("As found %v, want %v", found, lineErr)
+	}
+	var batch *BatchError
+	if (&BatchError{Errors: []error{ErrOpen}}).As(&batch) {
+		t.Error("As found a BatchError in errors without one")
+	}
+}
+
+func TestCommentStyles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		//a connector in a comment of the file's language doesn't join lines
+		"a.py":  "x = 1  # note \\\ny = 2 // \\\nz\n",
+		"a.go":  "x := 1 // note \\\ny := 2 # \\\nz\n",
+		"a.txt": "x # \\\ny // \\\nz\n",
+	}
+	want := map[string]string{
+		"a.py":  "x = 1  # note \\\ny = 2 // z\n\n",
+		"a.go":  "x := 1 // note \\\ny := 2 # z\n\n",
+		"a.txt": "x # y // z\n\n\n",
+	}
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, cleanUp, err := UnwrapDir(dir, "*", Options{CommentStyles: DefaultCommentStyles, TempDir: t.TempDir()})
+	defer cleanUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		b, err := ioutil.ReadFile(results[filepath.Join(dir, name)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want[name] {
+			t.Errorf("%s unwrapped to %q, want %q", name, b, want[name])
+		}
+	}
+}
+
+func TestMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("a \\\nb\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filePaths := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+
+	tests := []struct {
+		name   string
+		unwrap func(opts Options) (map[string]string, func(), error)
+	}{
+		{"UnwrapFiles", func(opts Options) (map[string]string, func(), error) { return UnwrapFiles(filePaths, opts) }},
+		{"UnwrapDir", func(opts Options) (map[string]string, func(), error) { return UnwrapDir(dir, "*.txt", opts) }},
+		{"UnwrapGlob", func(opts Options) (map[string]string, func(), error) {
+			return UnwrapGlob(filepath.Join(dir, "*.txt"), opts)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, cleanUp, err := test.unwrap(Options{MaxFiles: 1, TempDir: t.TempDir()})
+			defer cleanUp()
+			if !errors.Is(err, ErrTooManyFiles) || len(results) > 0 {
+				t.Errorf("got %v and %d results, want %v before any unwrap", err, len(results), ErrTooManyFiles)
+			}
+
+			results, cleanUp, err = test.unwrap(Options{MaxFiles: 2, TempDir: t.TempDir()})
+			defer cleanUp()
+			if err != nil || len(results) != 2 {
+				t.Errorf("at the limit got %v and %d results", err, len(results))
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	dir := t.TempDir()
+	filePaths := []string{
+		writeTemp(t, "a.txt", "a \\\nb\n"),
+		filepath.Join(dir, "missing.txt"),
+		writeTemp(t, "c.txt", "c\n"),
+	}
+
+	var done []int
+	opts := Options{TempDir: t.TempDir(), Progress: func(n, total int) {
+		if total != len(filePaths) {
+			t.Errorf("total = %d, want %d", total, len(filePaths))
+		}
+		done = append(done, n)
+	}}
+	_, cleanUp, _ := UnwrapFiles(filePaths, opts)
+	defer cleanUp()
+	if !reflect.DeepEqual(done, []int{1, 2, 3}) {
+		t.Errorf("progress reported %v, once per file failed or not", done)
+	}
+}
+
+func TestUnwrapDirContext(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("a \\\nb\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, cleanUp, err := UnwrapDirContext(ctx, dir, "*.txt", Options{TempDir: t.TempDir()})
+	cleanUp()
+	if !errors.Is(err, context.Canceled) || len(results) > 0 {
+		t.Errorf("cancelled walk got %v and %d results", err, len(results))
+	}
+
+	//cancelled after the first file, its temp file is still removed by cleanUp
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	tmpDir := t.TempDir()
+	opts := Options{TempDir: tmpDir, Progress: func(done, total int) { cancel() }}
+	results, cleanUp, err = UnwrapDirContext(ctx, dir, "*.txt", opts)
+	if !errors.Is(err, context.Canceled) || len(results) != 1 {
+		t.Fatalf("got %v and %d results, want to stop after the first file", err, len(results))
+	}
+	cleanUp()
+	if infos, err := ioutil.ReadDir(tmpDir); err != nil || len(infos) > 0 {
+		t.Errorf("cleanUp left %d temp files, %v", len(infos), err)
+	}
+}
+
+func TestUnwrapDirMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "missing")
+	results, cleanUp, err := UnwrapDir(root, "*.txt", Options{TempDir: t.TempDir()})
+	defer cleanUp()
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, os.ErrNotExist) || len(results) > 0 {
+		t.Errorf("got %v and %d results, want a walk error", err, len(results))
+	}
+}
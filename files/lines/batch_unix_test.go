@@ -0,0 +1,173 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package lines
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+//slowFile returns a named pipe in dir that is written a line every few milliseconds for a second
+func slowFile(t *testing.T, dir string) string {
+	t.Helper()
+	filePath := filepath.Join(dir, "slow.txt")
+	if err := syscall.Mkfifo(filePath, 0644); err != nil {
+		t.Skipf("can't make a named pipe: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer w.Close()
+		for end := time.Now().Add(time.Second); time.Now().Before(end); time.Sleep(5 * time.Millisecond) {
+			if _, err := w.WriteString("slow line \\\n"); err != nil {
+				return //the reader gave up
+			}
+		}
+	}()
+	t.Cleanup(func() { <-done })
+	return filePath
+}
+
+func TestPerFileTimeout(t *testing.T) {
+	dir := t.TempDir()
+	fast := writeTemp(t, "fast.txt", "a \\\nb\n")
+	slow := slowFile(t, dir)
+
+	results, cleanUp, err := UnwrapFiles([]string{slow, fast}, Options{PerFileTimeout: 100 * time.Millisecond, TempDir: t.TempDir()})
+	defer cleanUp()
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the slow file to time out", err)
+	}
+	if _, ok := results[fast]; !ok || len(results) != 1 {
+		t.Errorf("got results %v, want only %s", results, fast)
+	}
+}
+
+//stuckFile returns a named pipe in dir whose writer opens it and never writes until the test ends
+func stuckFile(t *testing.T, dir string) string {
+	t.Helper()
+	filePath := filepath.Join(dir, "stuck.txt")
+	if err := syscall.Mkfifo(filePath, 0644); err != nil {
+		t.Skipf("can't make a named pipe: %v", err)
+	}
+
+	end := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer w.Close()
+		<-end
+	}()
+	t.Cleanup(func() {
+		close(end)
+		<-done
+	})
+	return filePath
+}
+
+func TestPerFileTimeoutBlockedRead(t *testing.T) {
+	stuck := stuckFile(t, t.TempDir())
+	fast := writeTemp(t, "fast.txt", "a \\\nb\n")
+
+	finished := make(chan struct{})
+	var results map[string]string
+	var err error
+	go func() {
+		defer close(finished)
+		var cleanUp func()
+		results, cleanUp, err = UnwrapFiles([]string{stuck, fast}, Options{PerFileTimeout: 100 * time.Millisecond, TempDir: t.TempDir()})
+		cleanUp()
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a blocked read outlasted PerFileTimeout")
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the stuck file to time out", err)
+	}
+	if _, ok := results[fast]; !ok || len(results) != 1 {
+		t.Errorf("got results %v, want only %s", results, fast)
+	}
+}
+
+func TestUnwrapContextBlockedRead(t *testing.T) {
+	stuck := stuckFile(t, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	finished := make(chan error)
+	go func() {
+		_, cleanUp, err := UnwrapContext(ctx, stuck, Options{TempDir: t.TempDir()})
+		cleanUp()
+		finished <- err
+	}()
+	select {
+	case err := <-finished:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a blocked read outlasted cancelling ctx")
+	}
+}
+
+func TestUnwrapDirWalkErrors(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root reads directories without permission")
+	}
+	root := t.TempDir()
+	for name, dir := range map[string]string{"a.txt": root, "b.txt": filepath.Join(root, "locked")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("a \\\nb\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	locked := filepath.Join(root, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	tests := []struct {
+		name     string
+		strategy ErrorStrategy
+		unwraps  int
+	}{
+		{"collect", CollectErrors, 1},
+		{"stop", StopOnError, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, cleanUp, err := UnwrapDir(root, "*.txt", Options{ErrorStrategy: test.strategy, TempDir: t.TempDir()})
+			defer cleanUp()
+			if !errors.Is(err, os.ErrPermission) {
+				t.Fatalf("got %v, want a permission error", err)
+			}
+			if len(results) != test.unwraps {
+				t.Errorf("unwrapped %v, want %d files", results, test.unwraps)
+			}
+		})
+	}
+}
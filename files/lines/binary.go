@@ -0,0 +1,58 @@
+package lines
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+//ErrBinaryInput is returned when a file to unwrap looks binary, see Options.AllowBinary
+var ErrBinaryInput = errors.New("binary input")
+
+//sniffLen is how many bytes at the start of a file are looked at to tell binary input
+const sniffLen = 8000
+
+//textReader returns a reader reading in from the start, or ErrBinaryInput when the first
+//sniffLen bytes of in look binary and binary input isn't allowed
+func (opts Options) textReader(filePath string, in io.Reader) (io.Reader, error) {
+	if opts.AllowBinary {
+		return in, nil
+	}
+
+	r := bufio.NewReaderSize(in, sniffLen)
+	chunk, _ := r.Peek(sniffLen) //a read error is returned by the next read of r
+	if err := opts.checkText(filePath, chunk); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//checkText returns ErrBinaryInput when b has a NUL byte or mostly isn't UTF-8
+func (opts Options) checkText(filePath string, b []byte) error {
+	if len(b) > sniffLen {
+		b = b[:sniffLen]
+	}
+	if bytes.IndexByte(b, 0) < 0 && invalidUTF8(b)*4 <= len(b) {
+		return nil
+	}
+	err := fmt.Errorf("%w: %s", ErrBinaryInput, filePath)
+	opts.logger().Warningf(err.Error())
+	return err
+}
+
+//invalidUTF8 counts bytes of b that aren't valid UTF-8,
+//a rune cut at the end of b isn't counted
+func invalidUTF8(b []byte) int {
+	invalid := 0
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 && utf8.FullRune(b) {
+			invalid++
+		}
+		b = b[size:]
+	}
+	return invalid
+}
@@ -0,0 +1,84 @@
+package lines
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//entryPoints unwraps filePath with functions reading files, see TestBinaryInput
+var entryPoints = []struct {
+	name   string
+	unwrap func(filePath string) error
+}{
+	{"Unwrap", func(filePath string) error {
+		_, cleanUp, err := Unwrap(filePath)
+		cleanUp()
+		return err
+	}},
+	{"UnwrapChunks", func(filePath string) error {
+		_, err := UnwrapChunks(filePath, 16)
+		return err
+	}},
+	{"DirStats", func(filePath string) error {
+		_, err := DirStats(filepath.Dir(filePath), "", "\\")
+		return err
+	}},
+	{"UnwrapTail", func(filePath string) error {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		return UnwrapTail(ctx, filePath, func(line string) error {
+			cancel()
+			return nil
+		})
+	}},
+}
+
+func TestBinaryInput(t *testing.T) {
+	for _, entryPoint := range entryPoints {
+		t.Run(entryPoint.name, func(t *testing.T) {
+			filePath := writeTemp(t, "binary.bin", "a\x00b \\\n"+strings.Repeat("c", 64)+"\n")
+			if err := entryPoint.unwrap(filePath); !errors.Is(err, ErrBinaryInput) {
+				t.Errorf("binary input got %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenError(t *testing.T) {
+	for _, entryPoint := range entryPoints {
+		if entryPoint.name == "DirStats" {
+			continue //a missing root is a walk error
+		}
+		t.Run(entryPoint.name, func(t *testing.T) {
+			if err := entryPoint.unwrap(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, ErrOpen) {
+				t.Errorf("missing file got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckText(t *testing.T) {
+	tests := []struct {
+		name string
+		b    string
+		text bool
+	}{
+		{"text", "a \\\nb\n", true},
+		{"UTF-8", "héllo wörld\n", true},
+		{"empty", "", true},
+		{"NUL byte", "a\x00b\n", false},
+		{"mostly invalid UTF-8", "\xff\xfe\xfd\xfca\n", false},
+		{"some invalid UTF-8", "latin1 caf\xe9 text\n", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Options{}.checkText("test", []byte(test.b))
+			if (err == nil) != test.text {
+				t.Errorf("checkText(%q) = %v", test.b, err)
+			}
+		})
+	}
+}
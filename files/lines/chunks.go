@@ -0,0 +1,95 @@
+package lines
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/google/logger"
+)
+
+//ErrLineSplit is the error of a LineError for a logical line UnwrapChunks split across chunks
+var ErrLineSplit = errors.New("line split across chunks")
+
+//UnwrapChunks unwraps filePath and splits the result into chunks of at most maxBytes bytes.
+//Chunks end at logical line boundaries, a logical line (with its line endings) longer than
+//maxBytes is split at rune boundaries into chunks of its own. All chunks are returned also
+//when lines were split, err is then a BatchError with a LineError matching ErrLineSplit
+//for every split line. Binary input fails with ErrBinaryInput as Unwrap does
+func UnwrapChunks(filePath string, maxBytes int) (chunks [][]byte, err error) {
+	if maxBytes <= 0 {
+		message := fmt.Sprintf("Chunk size must be positive: %d", maxBytes)
+		log.Warningf(message)
+		return nil, errors.New(message)
+	}
+
+	opts := Options{}.forFile(filePath)
+	file, err := opts.openFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	in, err := opts.textReader(filePath, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunk []byte
+	var lineBuilder strings.Builder
+	var splits []error
+
+	u := opts.newUnwrapper(wrap, func(line logicalLine) error {
+		lineBuilder.Reset()
+		if err := opts.writeLine(&lineBuilder, line); err != nil {
+			return err
+		}
+		text := lineBuilder.String()
+
+		if len(chunk)+len(text) <= maxBytes {
+			chunk = append(chunk, text...)
+			return nil
+		}
+		if len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+			chunk = nil
+		}
+		if len(text) <= maxBytes {
+			chunk = append(chunk, text...)
+			return nil
+		}
+
+		splits = append(splits, opts.lineError(line.first, fmt.Errorf("%w: longer than %d bytes", ErrLineSplit, maxBytes)))
+		for len(text) > maxBytes {
+			cut := maxBytes
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = maxBytes
+			}
+			chunks = append(chunks, []byte(text[:cut]))
+			text = text[cut:]
+		}
+		chunk = append(chunk, text...)
+		return nil
+	})
+
+	if err := u.opts.forEachLineIn(in, u.add); err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		log.Warningf(err.Error())
+		return nil, err
+	}
+	if err := u.close(); err != nil {
+		return nil, err
+	}
+
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	if len(splits) > 0 {
+		return chunks, &BatchError{Errors: splits}
+	}
+	return chunks, nil
+}
@@ -0,0 +1,53 @@
+package lines
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnwrapChunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxBytes int
+		want     []string
+		split    []int //lines split across chunks
+	}{
+		{"one chunk", "a\nb\n", 10, []string{"a\nb\n"}, nil},
+		{"chunks end at lines", "aa\nbb\ncc\n", 6, []string{"aa\nbb\n", "cc\n"}, nil},
+		{"joined line stays whole", "a \\\nb\nc\n", 5, []string{"a b\n\n", "c\n"}, nil},
+		{"long line", "abcdef\nx\n", 4, []string{"abcd", "ef\n", "x\n"}, []int{1}},
+		{"cut at a rune boundary", "aéé\n", 4, []string{"aé", "é\n"}, []int{1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunks, err := UnwrapChunks(writeTemp(t, "chunks.txt", test.text), test.maxBytes)
+
+			var split []int
+			var batchErr *BatchError
+			if errors.As(err, &batchErr) {
+				for _, err := range batchErr.Errors {
+					var lineErr *LineError
+					if !errors.As(err, &lineErr) || !errors.Is(err, ErrLineSplit) {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					split = append(split, lineErr.Line)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(chunks))
+			for i, chunk := range chunks {
+				got[i] = string(chunk)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("chunks = %q, want %q", got, test.want)
+			}
+			if !reflect.DeepEqual(split, test.split) {
+				t.Errorf("split lines = %v, want %v", split, test.split)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package lines
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+//noCleanUp is the clean up of nothing, returned when no temp file was created
+func noCleanUp() error {
+	return nil
+}
+
+//cleanUpOnce makes cleanUp safe to call more than once, e.g. in deferred chains: only the
+//first call cleans up and returns its error, later calls do nothing and return nil
+func cleanUpOnce(cleanUp func() error) func() error {
+	var once sync.Once
+	return func() (err error) {
+		once.Do(func() { err = cleanUp() })
+		return err
+	}
+}
+
+//removeOnce returns a clean up removing temp file name once, see cleanUpOnce
+func (opts Options) removeOnce(name string) func() error {
+	return cleanUpOnce(func() error {
+		if err := opts.remove(name); err != nil {
+			err = fmt.Errorf("Failed to remove temp file: %s: %w", name, err)
+			opts.logger().Warningf(err.Error())
+			return err
+		}
+		return nil
+	})
+}
+
+//cachedHolders counts callers in this process holding every cached temp file, see holdCached
+var cachedHolders = struct {
+	sync.Mutex
+	count map[string]int
+}{count: map[string]int{}}
+
+//reuseCached returns the clean up of a caller reusing cached temp file name
+//when it exists, see holdCached
+func (opts Options) reuseCached(name string) (cleanUp func() error, ok bool) {
+	cachedHolders.Lock()
+	defer cachedHolders.Unlock()
+
+	if _, err := os.Stat(name); err != nil {
+		return noCleanUp, false
+	}
+	return opts.holdCached(name), true
+}
+
+//placeCached renames tmpName to cached temp file name, or removes it when name exists already
+//and is reused. Returns whether name was reused and the clean up of the caller, see holdCached
+func (opts Options) placeCached(tmpName string, name string) (reused bool, cleanUp func() error, err error) {
+	cachedHolders.Lock()
+	defer cachedHolders.Unlock()
+
+	if _, err := os.Stat(name); err == nil {
+		os.Remove(tmpName)
+		return true, opts.holdCached(name), nil
+	}
+	if err := os.Rename(tmpName, name); err != nil {
+		return false, noCleanUp, writeError(err)
+	}
+	return false, opts.holdCached(name), nil
+}
+
+//holdCached adds a holder of cached temp file name, cachedHolders must be locked. The returned
+//clean up removes the file when the last holder cleans up, so a caller cleaning up never
+//removes the file while other callers still use it
+func (opts Options) holdCached(name string) func() error {
+	cachedHolders.count[name]++
+	remove := opts.removeOnce(name)
+	return cleanUpOnce(func() error {
+		cachedHolders.Lock()
+		defer cachedHolders.Unlock()
+
+		if cachedHolders.count[name]--; cachedHolders.count[name] > 0 {
+			return nil
+		}
+		delete(cachedHolders.count, name)
+		return remove()
+	})
+}
+
+//ignoreError adapts cleanUp to functions returning a clean up without an error
+func ignoreError(cleanUp func() error) func() {
+	return func() { cleanUp() }
+}
+
+//Cleaner collects clean ups of several unwraps to run them at once, e.g.
+//	c := lines.NewCleaner()
+//	defer c.Close()
+//	lines.UnwrapWithOptions(filePath, lines.Options{Cleaner: c})
+//It is safe for concurrent use
+type Cleaner struct {
+	//KeepOnError keeps temp files of unwraps that failed for debugging, Close doesn't remove them
+	KeepOnError bool
+
+	mu       sync.Mutex
+	cleanUps []func() error
+}
+
+//NewCleaner returns a Cleaner without clean ups
+func NewCleaner() *Cleaner {
+	return &Cleaner{}
+}
+
+//Add registers cleanUp to run on Close
+func (c *Cleaner) Add(cleanUp func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cleanUps = append(c.cleanUps, cleanUp)
+}
+
+//Close runs registered clean ups, the last registered first, and forgets them.
+//Returns the first error a clean up returned
+func (c *Cleaner) Close() error {
+	c.mu.Lock()
+	cleanUps := c.cleanUps
+	c.cleanUps = nil
+	c.mu.Unlock()
+
+	var firstErr error
+	for i := len(cleanUps) - 1; i >= 0; i-- {
+		if err := cleanUps[i](); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+//register adds cleanUp of newFilePath to Options.Cleaner when it is set, unless the unwrap
+//failed with err and the cleaner keeps temp files on error. Returns the clean up to return
+func (opts Options) register(newFilePath string, cleanUp func() error, err error) func() error {
+	if opts.Cleaner == nil {
+		return cleanUp
+	}
+	if err != nil && newFilePath != "" && opts.Cleaner.KeepOnError {
+		opts.logger().Infof("Keeping temp file %s of a failed unwrap", newFilePath)
+		return noCleanUp
+	}
+	opts.Cleaner.Add(cleanUp)
+	return cleanUp
+}
@@ -0,0 +1,123 @@
+package lines
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemove(t *testing.T) {
+	var removed []string
+	opts := Options{TempDir: t.TempDir(), Remove: func(name string) error {
+		removed = append(removed, name)
+		return os.Remove(name)
+	}}
+
+	newFilePath, cleanUp, err := UnwrapWithOptions(writeTemp(t, "a.txt", "a \\\nb\n"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanUp()
+	if len(removed) != 1 || removed[0] != newFilePath {
+		t.Errorf("removed %q, want %q", removed, newFilePath)
+	}
+	if _, err := os.Stat(newFilePath); !os.IsNotExist(err) {
+		t.Errorf("temp file kept: %v", err)
+	}
+}
+
+func TestCleanUpTwice(t *testing.T) {
+	result, err := UnwrapWithResult(writeTemp(t, "a.txt", "a \\\nb\n"), Options{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		if err := result.CleanUp(); err != nil {
+			t.Errorf("call %d of CleanUp got %v", i, err)
+		}
+	}
+	if _, err := os.Stat(result.Path); !os.IsNotExist(err) {
+		t.Errorf("temp file kept: %v", err)
+	}
+}
+
+func TestCleanUpError(t *testing.T) {
+	result, err := UnwrapWithResult(writeTemp(t, "a.txt", "a \\\nb\n"), Options{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//moved away before the clean up
+	if err := os.Rename(result.Path, filepath.Join(t.TempDir(), "moved")); err != nil {
+		t.Fatal(err)
+	}
+	if err := result.CleanUp(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("removing a moved file got %v", err)
+	}
+	if err := result.CleanUp(); err != nil {
+		t.Errorf("second CleanUp got %v, want nil", err)
+	}
+}
+
+func TestCleaner(t *testing.T) {
+	tests := []struct {
+		name        string
+		keepOnError bool
+		text        string
+		failed      bool
+		kept        bool //the temp file exists after Close
+	}{
+		{"unwrapped", false, "a \\\nb\n", false, false},
+		{"unwrapped, keep on error", true, "a \\\nb\n", false, false},
+		{"failed", false, "a \\\n", true, false},
+		{"failed, keep on error", true, "a \\\n", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCleaner()
+			c.KeepOnError = test.keepOnError
+			opts := Options{TempDir: t.TempDir(), FailOnDanglingConnector: true, Cleaner: c}
+
+			newFilePath, _, err := UnwrapWithOptions(writeTemp(t, "a.txt", test.text), opts)
+			if (err != nil) != test.failed {
+				t.Fatalf("got %v, want an error: %t", err, test.failed)
+			}
+			if newFilePath == "" {
+				t.Fatal("no temp file returned")
+			}
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(newFilePath); (err == nil) != test.kept {
+				t.Errorf("temp file kept: %t, want %t", err == nil, test.kept)
+			}
+		})
+	}
+}
+
+func TestCleanerOrder(t *testing.T) {
+	c := NewCleaner()
+	var order []int
+	for i := 1; i <= 3; i++ {
+		i := i
+		c.Add(func() error {
+			order = append(order, i)
+			return nil
+		})
+	}
+	errFirst := errors.New("first")
+	c.Add(func() error { return errors.New("second") })
+	c.Add(func() error { return errFirst })
+
+	if err := c.Close(); err != errFirst {
+		t.Errorf("got %v, want the first error", err)
+	}
+	if len(order) != 3 || order[0] != 3 || order[2] != 1 {
+		t.Errorf("ran clean ups in order %v, want the last registered first", order)
+	}
+	//clean ups are forgotten
+	order = nil
+	if err := c.Close(); err != nil || len(order) > 0 {
+		t.Errorf("second Close got %v and ran %v", err, order)
+	}
+}
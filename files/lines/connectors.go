@@ -0,0 +1,173 @@
+package lines
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+//ErrDanglingConnector is returned in a LineError with Options.FailOnDanglingConnector when the
+//last line ends with a connector, so there is no line to join it with
+var ErrDanglingConnector = errors.New("dangling connector")
+
+//ErrTooManyContinuations is returned in a LineError when a wrap run is longer than Options.MaxContinuations
+var ErrTooManyContinuations = errors.New("too many continuations")
+
+//ConnectorRule is a connector with its own comment and string awareness, see Options.Connectors.
+//When connectors of several rules match the end of a line the longest one wins and only its
+//rule decides, a shorter connector is never tried instead of an ignored longer one
+type ConnectorRule struct {
+	//Connector is the line ending that joins a line with the next one
+	Connector string
+	//IgnoreInComments leaves Connector as it is when it is inside a line comment
+	//started by Options.CommentPrefix
+	IgnoreInComments bool
+	//IgnoreInStrings leaves Connector as it is when it is inside a string
+	//quoted with one of Options.Quotes that is still open at the end of the line
+	IgnoreInStrings bool
+}
+
+//defaultQuotes are quote characters used when Options.Quotes is empty
+const defaultQuotes = "\"'"
+
+//connectorRules returns rules from opts, or a rule for connector when there are none,
+//longest connector first
+func (opts Options) connectorRules(connector string) []ConnectorRule {
+	if len(opts.Connectors) == 0 {
+		//comment awareness of the only connector depends on CommentPrefix alone
+		return []ConnectorRule{{Connector: connector, IgnoreInComments: true, IgnoreInStrings: opts.QuoteAware}}
+	}
+
+	rules := append([]ConnectorRule(nil), opts.Connectors...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Connector) > len(rules[j].Connector)
+	})
+	return rules
+}
+
+func (opts Options) validateConnectors() error {
+	for _, rule := range opts.Connectors {
+		if rule.Connector == "" {
+			message := "Connector must not be empty"
+			opts.logger().Warningf(message)
+			return errors.New(message)
+		}
+	}
+	return nil
+}
+
+//continues returns the active connector a physical line ends with or "".
+//When several connectors match the end of the line the longest one wins and only its rule
+//decides: when it is inside a comment or a string it ignores, the line doesn't continue
+//even if a shorter matching connector would be active there
+func (u *unwrapper) continues(text string) string {
+	for _, rule := range u.rules {
+		if !strings.HasSuffix(text, rule.Connector) {
+			continue
+		}
+		ignoreInComments := rule.IgnoreInComments && u.opts.CommentPrefix != ""
+		if !ignoreInComments && !rule.IgnoreInStrings {
+			return rule.Connector
+		}
+
+		inString, inComment := u.scanLine(text[:len(text)-len(rule.Connector)])
+		if (inComment && ignoreInComments) || (inString && rule.IgnoreInStrings) {
+			return ""
+		}
+		return rule.Connector
+	}
+	return ""
+}
+
+//scanLine tells if a string or a line comment is open at the end of text.
+//A comment prefix inside a string doesn't start a comment, inside strings
+//a backslash escapes the next character
+func (u *unwrapper) scanLine(text string) (inString bool, inComment bool) {
+	quotes := u.opts.Quotes
+	if quotes == "" {
+		quotes = defaultQuotes
+	}
+
+	var quote rune
+	escaped := false
+	for i, r := range text {
+		switch {
+		case quote != 0 && escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case u.opts.CommentPrefix != "" && strings.HasPrefix(text[i:], u.opts.CommentPrefix):
+			return false, true
+		case strings.ContainsRune(quotes, r):
+			quote = r
+		}
+	}
+	return quote != 0, false
+}
+
+//isJoint tells if text holds nothing but a connector, see paragraph.go
+func (u *unwrapper) isJoint(text string) bool {
+	text = u.trimLeft(text)
+	return text != "" && u.continues(text) == text
+}
+
+//brokenPair tells if the run so far ends with a connector but text doesn't start with
+//Options.ContinuationPrefix, so the run ends before text and keeps its connector
+func (u *unwrapper) brokenPair(text string) bool {
+	if u.opts.ContinuationPrefix == "" || len(u.run.lines) == 0 || u.run.lines[len(u.run.lines)-1].connector == "" {
+		return false
+	}
+	return !strings.HasPrefix(u.trimLeft(text), u.opts.ContinuationPrefix)
+}
+
+//unescapeConnectors collapses every doubled connector at the end of text into one literal
+//connector, see Options.EscapeDoubledConnector. Returns the connector the line still
+//wraps with, "" when all connectors at the end of text were escaped
+func unescapeConnectors(text string, connector string) (string, string) {
+	if connector == "" {
+		return text, ""
+	}
+	base := text
+	count := 0
+	for strings.HasSuffix(base, connector) {
+		base = strings.TrimSuffix(base, connector)
+		count++
+	}
+
+	text = base + strings.Repeat(connector, count/2)
+	if count%2 == 0 {
+		return text, ""
+	}
+	return text + connector, connector
+}
+
+//stripConnectors strips connectors still at the end of a logical line, e.g. of a doubled
+//dangling connector or of a joined line ending with blank lines, so unwrapping the unwrapped
+//text again doesn't join it with the next line. Literal connectors of an escape at the end of
+//run are left as they are, so are connectors with Options.ContinuationPrefix: they stay in
+//place when the next line doesn't start with the prefix, see brokenPair
+func (u *unwrapper) stripConnectors(run logicalLine, text string) string {
+	if run.lines[len(run.lines)-1].escaped || u.openTag != "" || u.opts.ContinuationPrefix != "" {
+		return text
+	}
+	for {
+		trimmed := u.trimRight(text)
+		connector := u.continues(trimmed)
+		if connector == "" {
+			return text
+		}
+		text = strings.TrimSuffix(trimmed, connector)
+	}
+}
+
+//checkDangling fails with ErrDanglingConnector when Options.FailOnDanglingConnector is set
+//and the run ends with a connector, called when the input ends
+func (u *unwrapper) checkDangling() error {
+	if !u.opts.FailOnDanglingConnector || len(u.run.lines) == 0 || u.run.lines[len(u.run.lines)-1].connector == "" {
+		return nil
+	}
+	return u.opts.lineError(u.lineNo, ErrDanglingConnector)
+}
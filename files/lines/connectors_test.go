@@ -0,0 +1,201 @@
+package lines
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestContinuationPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"joined", "a \\\n>b\n", "a b\n\n"},
+		{"indented prefix", "a \\\n  >b\n", "a b\n\n"},
+		{"white space after the prefix kept", "a \\\n> b\n", "a  b\n\n"},
+		{"connector kept without prefix", "a\\\nb\n", "a\\\nb\n"},
+		{"connector kept on the last line", "a\\\n", "a\\\n"},
+		{"prefix without connector", "a\n> b\n", "a\n> b\n"},
+		{"three lines", "a \\\n>b \\\n>c\n", "a b c\n\n\n"},
+		{"run broken in the middle", "a \\\n>b \\\nc\n", "a b \\\n\nc\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{ContinuationPrefix: ">"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestContinuationPrefixOptions(t *testing.T) {
+	if _, err := UnwrapStringWithOptions("a\n", Options{ContinuationPrefix: ">", SpillThreshold: 1 << 10}); err == nil {
+		t.Error("ContinuationPrefix with SpillThreshold got no error")
+	}
+}
+
+func TestConnectorRules(t *testing.T) {
+	opts := Options{
+		CommentPrefix: "#",
+		Connectors: []ConnectorRule{
+			{Connector: "\\", IgnoreInComments: true, IgnoreInStrings: true},
+			{Connector: "&&"},
+		},
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"code connector", "a \\\nb\n", "a b\n\n"},
+		{"code connector in comment", "a # c \\\nb\n", "a # c \\\nb\n"},
+		{"code connector in string", "a 'c \\\nb\n", "a 'c \\\nb\n"},
+		{"comment-safe connector", "a &&\nb\n", "a b\n\n"},
+		{"comment-safe connector in comment", "a # c &&\nb\n", "a # c b\n\n"},
+		{"both in one file", "a # \\\nb # &&\nc\n", "a # \\\nb # c\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestConnectorRulesLongestWins(t *testing.T) {
+	//"\\\\" ignores comments, so the shorter "\\" isn't tried in one
+	opts := Options{
+		CommentPrefix: "#",
+		Connectors: []ConnectorRule{
+			{Connector: "\\"},
+			{Connector: "\\\\", IgnoreInComments: true},
+		},
+	}
+	text := "a # c \\\\\nb\n"
+	got, err := UnwrapStringWithOptions(text, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != text {
+		t.Errorf("UnwrapStringWithOptions(%q) = %q, want it unchanged", text, got)
+	}
+
+	if _, err := UnwrapStringWithOptions(text, Options{Connectors: []ConnectorRule{{}}}); err == nil {
+		t.Error("empty connector got no error")
+	}
+}
+
+func TestDanglingConnector(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		lenient  string
+		dangling int //line of ErrDanglingConnector, 0 for none
+	}{
+		{"last character", "a\nb \\", "a\nb ", 2},
+		{"last line", "a \\\nb \\\n", "a b \n\n", 2},
+		{"doubled connector", "a \\\\", "a ", 1},
+		{"no connector", "a\nb\n", "a\nb\n", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapString(test.text); got != test.lenient {
+				t.Errorf("UnwrapString(%q) = %q, want %q", test.text, got, test.lenient)
+			}
+
+			_, err := UnwrapStringWithOptions(test.text, Options{FailOnDanglingConnector: true})
+			var lineErr *LineError
+			switch {
+			case test.dangling == 0 && err != nil:
+				t.Errorf("got %v", err)
+			case test.dangling > 0 && (!errors.As(err, &lineErr) || !errors.Is(err, ErrDanglingConnector) || lineErr.Line != test.dangling):
+				t.Errorf("got %v, want %v at line %d", err, ErrDanglingConnector, test.dangling)
+			}
+		})
+	}
+}
+
+func TestQuoteAware(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		quotes string
+		want   string
+	}{
+		{"connector in a string", "x := \"foo\\\\\"\ny\n", "", "x := \"foo\\\\\"\ny\n"},
+		{"string left open", "x := \"foo \\\ny\"\n", "", "x := \"foo \\\ny\"\n"},
+		{"connector after a string", "x := \"foo\" \\\ny\n", "", "x := \"foo\" y\n\n"},
+		{"escaped quote", "x := \"a\\\"b\" \\\ny\n", "", "x := \"a\\\"b\" y\n\n"},
+		{"single quotes", "x = 'foo \\\ny'\n", "", "x = 'foo \\\ny'\n"},
+		{"configured quotes", "x = `foo \\\ny\nx = 'z \\\nw\n", "`", "x = `foo \\\ny\nx = 'z w\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{QuoteAware: true, Quotes: test.quotes})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapWithConnectors(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		connectors []string
+		want       string
+	}{
+		{"mixed markers", "a \\\nb\nc ~\nd\n", []string{"\\", "~"}, "a b\n\nc d\n\n"},
+		{"longest wins", "a \\\\\nb\n", []string{"\\", "\\\\"}, "a b\n\n"},
+		{"only one stripped", "a ~\\\nb\n", []string{"\\", "~"}, "a ~b\n\n"},
+		{"empty connectors skipped", "a \\\nb\n", []string{"", "~"}, "a \\\nb\n"},
+		{"no connectors", "a \\\nb\n", nil, "a b\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapWithConnectors(test.text, test.connectors...); got != test.want {
+				t.Errorf("UnwrapWithConnectors(%q, %q) = %q, want %q", test.text, test.connectors, got, test.want)
+			}
+		})
+	}
+}
+
+func TestConnectorEscapeWithConnector(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"one connector", "a &&\nb\n", "a b\n\n"},
+		{"two connectors", "a &&&&\nb\n", "a &&\nb\n"},
+		{"three connectors", "a &&&&&&\nb\n", "a &&b\n\n"},
+		{"backslashes", "a \\\\\nb\n", "a \\\\\nb\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := connectorOptions("&&")
+			opts.EscapeDoubledConnector = true
+			got, err := UnwrapStringWithOptions(test.text, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
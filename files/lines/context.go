@@ -0,0 +1,49 @@
+package lines
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+//contextReader reads from r until ctx is done, then fails with ctx.Err()
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	if err != nil && r.ctx.Err() != nil {
+		//a read cut off by watchContext
+		return n, r.ctx.Err()
+	}
+	return n, err
+}
+
+//watchContext cuts off a read of file blocked when ctx is done, if file supports read
+//deadlines (e.g. a pipe). Reads of other files are only checked between reads by
+//contextReader. Returns a function to stop watching, it must be called before file is closed
+func watchContext(ctx context.Context, file *os.File) (stop func()) {
+	if ctx.Done() == nil || file.SetReadDeadline(time.Time{}) != nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			file.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,104 @@
+package lines
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	log "github.com/google/logger"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+)
+
+//UnwrapAutoDetect works as Unwrap for files in an unknown encoding: it detects the encoding
+//of filePath, decodes it, unwraps decoded text and writes it UTF-8 encoded to the temp file.
+//The encoding is detected by a byte order mark (UTF-8, UTF-16LE, UTF-16BE) or, without one,
+//by NUL bytes of UTF-16 encoded ASCII text. Any other text is taken as UTF-8.
+//Returns: path to a temp file with unwrapped UTF-8 content
+//				 detected encoding of the source
+//				 function to clean up temp files
+//				 error if something went wrong
+func UnwrapAutoDetect(filePath string) (newFilePath string, detected encoding.Encoding, cleanUp func(), err error) {
+
+	cleanUp = func() {}
+
+	in, err := Options{}.openFile(filePath)
+	if err != nil {
+		return "", nil, cleanUp, err
+	}
+	defer in.Close()
+
+	b, err := ioutil.ReadAll(in)
+	if err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		log.Warningf(err.Error())
+		return "", nil, cleanUp, err
+	}
+
+	detected = detectEncoding(b)
+	decoded, err := detected.NewDecoder().Bytes(b)
+	if err != nil {
+		err = fmt.Errorf("Failed to decode file: %s: %w", filePath, err)
+		log.Warningf(err.Error())
+		return "", detected, cleanUp, err
+	}
+
+	text, err := Options{}.unwrapText(string(decoded))
+	if err != nil {
+		return "", detected, cleanUp, err
+	}
+
+	tmpFile, err := Options{}.tempFile(filePath)
+	if err != nil {
+		return "", detected, cleanUp, err
+	}
+	defer tmpFile.Close()
+
+	cleanUp = ignoreError(Options{}.removeOnce(tmpFile.Name()))
+
+	if _, err = tmpFile.WriteString(text); err != nil {
+		err = fmt.Errorf("Failed to write unwrapped text to: %s: %w", tmpFile.Name(), writeError(err))
+		log.Warningf(err.Error())
+		return tmpFile.Name(), detected, cleanUp, err
+	}
+
+	log.Infof("Successfuly unwrapped lines to temp file %s", tmpFile.Name())
+
+	return tmpFile.Name(), detected, cleanUp, nil
+}
+
+//detectEncoding guesses the encoding of b, see UnwrapAutoDetect
+func detectEncoding(b []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(b, []byte("\xEF\xBB\xBF")):
+		return unicode.UTF8BOM
+	case bytes.HasPrefix(b, []byte("\xFF\xFE")):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(b, []byte("\xFE\xFF")):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	}
+
+	//UTF-16 encoded ASCII has NUL in every other byte, it is valid UTF-8 too so it is
+	//looked for first
+	sample := b
+	if len(sample) > 4096 {
+		sample = sample[:4096]
+	}
+	evenNULs, oddNULs := 0, 0
+	for i, c := range sample {
+		if c == 0 {
+			if i%2 == 0 {
+				evenNULs++
+			} else {
+				oddNULs++
+			}
+		}
+	}
+	switch half := len(sample) / 4; {
+	case oddNULs > half && evenNULs == 0:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case evenNULs > half && oddNULs == 0:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	return unicode.UTF8
+}
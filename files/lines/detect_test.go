@@ -0,0 +1,48 @@
+package lines
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+		want encoding.Encoding
+	}{
+		{"UTF-8", []byte("a \\\nb\n"), unicode.UTF8},
+		{"UTF-8 BOM", []byte("\xEF\xBB\xBFa\n"), unicode.UTF8BOM},
+		{"UTF-16LE BOM", []byte("\xFF\xFEa\x00\n\x00"), unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)},
+		{"UTF-16BE BOM", []byte("\xFE\xFF\x00a\x00\n"), unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)},
+		{"UTF-16LE", []byte("a\x00 \x00\\\x00\n\x00b\x00\n\x00"), unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+		{"UTF-16BE", []byte("\x00a\x00 \x00\\\x00\n\x00b\x00\n"), unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := detectEncoding(test.b); got != test.want {
+				t.Errorf("detectEncoding(%q) = %v, want %v", test.b, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapAutoDetect(t *testing.T) {
+	filePath := writeTemp(t, "utf16.txt", "a\x00 \x00\\\x00\n\x00b\x00\n\x00")
+	newFilePath, _, cleanUp, err := UnwrapAutoDetect(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+
+	b, err := ioutil.ReadFile(newFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a b\n\n" {
+		t.Errorf("unwrapped to %q", b)
+	}
+}
@@ -0,0 +1,26 @@
+package lines
+
+import "fmt"
+
+//LineDirective is the format of directives written by Options.LineDirectives
+type LineDirective string
+
+const (
+	//CLineDirective writes `#line N "file"` as understood by C and C++ preprocessors
+	CLineDirective LineDirective = "#line"
+	//GoLineDirective writes `//line file:N` as understood by the Go compiler
+	GoLineDirective LineDirective = "//line"
+)
+
+//writeLineDirective writes a directive telling that the next line is source line lineNo
+func (opts Options) writeLineDirective(w lineWriter, lineNo int) error {
+	var directive string
+	switch opts.LineDirectives {
+	case CLineDirective:
+		directive = fmt.Sprintf("#line %d %q", lineNo, opts.LineDirectiveFile)
+	case GoLineDirective:
+		directive = fmt.Sprintf("//line %s:%d", opts.LineDirectiveFile, lineNo)
+	}
+	_, err := w.WriteString(directive + opts.lineEnding("\n"))
+	return err
+}
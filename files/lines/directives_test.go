@@ -0,0 +1,45 @@
+package lines
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestLineDirectives(t *testing.T) {
+	text := "a \\\nb\nc\n"
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"C", Options{LineDirectives: CLineDirective, LineDirectiveFile: "page.tmpl"}, "#line 1 \"page.tmpl\"\na b\n\n#line 3 \"page.tmpl\"\nc\n"},
+		{"Go", Options{LineDirectives: GoLineDirective, LineDirectiveFile: "page.tmpl"}, "//line page.tmpl:1\na b\n\n//line page.tmpl:3\nc\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLineDirectiveFile(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	newFilePath, cleanUp, err := UnwrapWithOptions(filePath, Options{LineDirectives: GoLineDirective, TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+	b, err := ioutil.ReadFile(newFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "//line " + filePath + ":1\na b\n\n"; string(b) != want {
+		t.Errorf("unwrapped to %q, want %q", b, want)
+	}
+}
@@ -0,0 +1,46 @@
+package lines
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/google/logger"
+)
+
+//UnwrapDocuments unwraps a multi-document text (e.g. YAML documents separated by "---")
+//and returns unwrapped documents without separator lines.
+//Every document is unwrapped on its own, so a wrap run never crosses a separator:
+//a connector on the last line before a separator is trimmed and does not join the separator.
+//Separators on the very first and on the last line don't produce empty documents
+func UnwrapDocuments(text string, connector string, separator string) ([]string, error) {
+	if connector == "" || separator == "" {
+		message := "Connector and document separator must not be empty"
+		log.Warningf(message)
+		return nil, errors.New(message)
+	}
+
+	var documents []string
+	var document strings.Builder
+	first := true
+	separated := false //the last line is a separator
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if strings.TrimRight(line, " \r\n\t") == separator {
+			if !first || document.Len() > 0 {
+				documents = append(documents, unwrapLinesInString(document.String(), connector))
+			}
+			document.Reset()
+			first = false
+			separated = true
+			continue
+		}
+		document.WriteString(line)
+		first = false
+		separated = separated && line == ""
+	}
+
+	if separated {
+		return documents, nil
+	}
+	return append(documents, unwrapLinesInString(document.String(), connector)), nil
+}
@@ -0,0 +1,40 @@
+package lines
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnwrapDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"one document", "a \\\nb\n", []string{"a b\n\n"}},
+		{"two documents", "a \\\nb\n---\nc\n", []string{"a b\n\n", "c\n"}},
+		{"leading separator", "---\na\n---\nb\n", []string{"a\n", "b\n"}},
+		{"trailing separator", "a\n---\nb\n---\n", []string{"a\n", "b\n"}},
+		{"trailing separator without line ending", "a\n---", []string{"a\n"}},
+		{"connector before separator", "a \\\n---\nb\n", []string{"a \n", "b\n"}},
+		{"empty document", "a\n---\n---\nb\n", []string{"a\n", "", "b\n"}},
+		{"empty text", "", []string{""}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapDocuments(test.text, "\\", "---")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("UnwrapDocuments(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapDocumentsEmptySeparator(t *testing.T) {
+	if _, err := UnwrapDocuments("a\n", "\\", ""); err == nil {
+		t.Error("an empty separator is accepted")
+	}
+}
@@ -0,0 +1,78 @@
+package lines
+
+import (
+	"strings"
+)
+
+//tagDelims are the delimiters of a template engine tag
+type tagDelims struct {
+	open, close string
+}
+
+//engineTags are tags of template engines supported by Options.Engine,
+//longer opening delimiters go first so they win over their prefixes.
+//Only comments of text/template are tags, actions are what lines are wrapped in
+var engineTags = map[string][]tagDelims{
+	"":              nil,
+	"text/template": {{"{{- /*", "*/"}, {"{{/*", "*/"}},
+	"jinja":         {{"{%", "%}"}, {"{#", "#}"}, {"{{", "}}"}},
+	"handlebars":    {{"{{!--", "--}}"}, {"{{", "}}"}},
+}
+
+//scanTags returns the closing delimiter of the tag open at the end of text,
+//open is the closing delimiter of a tag left open by previous lines or ""
+func scanTags(text string, tags []tagDelims, open string) string {
+	for {
+		if open != "" {
+			i := strings.Index(text, open)
+			if i < 0 {
+				return open
+			}
+			text, open = text[i+len(open):], ""
+			continue
+		}
+
+		first, next := -1, tagDelims{}
+		for _, tag := range tags {
+			if i := strings.Index(text, tag.open); i >= 0 && (first < 0 || i < first) {
+				first, next = i, tag
+			}
+		}
+		if first < 0 {
+			return ""
+		}
+		text, open = text[first+len(next.open):], next.close
+	}
+}
+
+//UnwrapForEngine unwraps filePath for the given template engine (see Options.Engine)
+//and returns the unwrapped content with a map from every output line to the source line
+//its text comes from, both starting with 1. Unwrapping keeps line numbers, so every
+//output line maps to the same number, the map lets callers not depend on that
+func UnwrapForEngine(filePath string, engine string) (string, map[int]int, error) {
+	text, err := Options{}.readFile(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := Options{Engine: engine}.forFile(filePath)
+	if err := opts.validate(); err != nil {
+		return "", nil, err
+	}
+
+	var out strings.Builder
+	sourceMap := map[int]int{}
+	u := opts.newUnwrapper(wrap, func(line logicalLine) error {
+		for i := range line.lines {
+			sourceMap[line.first+i] = line.first + i
+		}
+		return opts.writeLine(&out, line)
+	})
+	if err := forEachLine(text, u.add); err != nil {
+		return "", nil, err
+	}
+	if err := u.close(); err != nil {
+		return "", nil, err
+	}
+	return out.String(), sourceMap, nil
+}
@@ -0,0 +1,60 @@
+package lines
+
+import (
+	"testing"
+)
+
+func TestUnwrapForEngine(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine string
+		text   string
+		want   string
+	}{
+		{"jinja block", "jinja", "{% if a \\\n   and b %}\nc \\\nd\n", "{% if a \\\n   and b %}\nc d\n\n"},
+		{"jinja comment", "jinja", "{# a \\\nb #} c \\\nd\n", "{# a \\\nb #} c d\n\n"},
+		{"handlebars", "handlebars", "{{!-- a \\\nb --}}\n", "{{!-- a \\\nb --}}\n"},
+		{"no engine", "", "{% if a \\\n   and b %}\n", "{% if a and b %}\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, sourceMap, err := UnwrapForEngine(writeTemp(t, "page.tmpl", test.text), test.engine)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapForEngine(%q) = %q, want %q", test.text, got, test.want)
+			}
+			for line, source := range sourceMap {
+				if line != source {
+					t.Errorf("line %d maps to %d", line, source)
+				}
+			}
+		})
+	}
+}
+
+func TestUnwrapForEngineUnsupported(t *testing.T) {
+	if _, _, err := UnwrapForEngine(writeTemp(t, "page.tmpl", "a\n"), "mustache"); err == nil {
+		t.Error("unsupported engine got no error")
+	}
+}
+
+func TestScanTags(t *testing.T) {
+	tags := engineTags["jinja"]
+	tests := []struct {
+		text string
+		open string
+		want string
+	}{
+		{"a {% b", "", "%}"},
+		{"a {% b %} c", "", ""},
+		{"b %} {{ c", "%}", "}}"},
+		{"still open", "#}", "#}"},
+	}
+	for _, test := range tests {
+		if got := scanTags(test.text, tags, test.open); got != test.want {
+			t.Errorf("scanTags(%q, %q) = %q, want %q", test.text, test.open, got, test.want)
+		}
+	}
+}
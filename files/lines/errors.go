@@ -0,0 +1,81 @@
+package lines
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+//ErrOpen is matched by errors opening a source file, ErrWrite by errors creating or writing to
+//where unwrapped text goes. Both still match the error they are caused with, e.g. fs.ErrNotExist
+var (
+	ErrOpen  = errors.New("open failed")
+	ErrWrite = errors.New("write failed")
+)
+
+//ErrInvalidUTF8 is the error of a LineError for a line that isn't valid UTF-8, see Options.ValidateUTF8
+var ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+//ioError is a failure matching kind (ErrOpen or ErrWrite) and the error it wraps
+type ioError struct {
+	kind    error
+	message string
+	err     error
+}
+
+func (e *ioError) Error() string {
+	return e.message
+}
+
+func (e *ioError) Unwrap() error {
+	return e.err
+}
+
+func (e *ioError) Is(target error) bool {
+	return target == e.kind
+}
+
+//LineError is a failure at a line of the source, Err tells what failed
+//(e.g. ErrDanglingConnector). File is empty when the source isn't a file
+type LineError struct {
+	File string
+	//Line starts with 1
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+//lineError returns a LineError at lineNo of the file opts are for, see forFile, and logs it
+func (opts Options) lineError(lineNo int, err error) error {
+	lineErr := &LineError{File: opts.filePath, Line: lineNo, Err: err}
+	opts.logger().Warningf(lineErr.Error())
+	return lineErr
+}
+
+//writeError makes err match ErrWrite
+func writeError(err error) error {
+	return &ioError{kind: ErrWrite, message: err.Error(), err: err}
+}
+
+//writeErrors makes errors writing to w match ErrWrite
+type writeErrors struct {
+	w io.Writer
+}
+
+func (w writeErrors) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if err != nil {
+		err = writeError(err)
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+package lines
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/google/logger"
+)
+
+//frontMatterDelimiter opens and closes front matter
+const frontMatterDelimiter = "---"
+
+//UnwrapWithFrontMatter unwraps text starting with front matter between two "---" lines
+//(e.g. YAML front matter of Markdown) and returns the unwrapped front matter and body
+//without delimiter lines. Front matter and body are unwrapped on their own, so a wrap run
+//never crosses a delimiter. Text not starting with a delimiter line has no front matter
+//and is returned as body. An unclosed front matter is an error
+func UnwrapWithFrontMatter(text string, connector string) (frontMatter string, body string, err error) {
+	if connector == "" {
+		message := "Connector must not be empty"
+		log.Warningf(message)
+		return "", "", errors.New(message)
+	}
+
+	isDelimiter := func(line string) bool {
+		return strings.TrimRight(line, " \r\n\t") == frontMatterDelimiter
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if !isDelimiter(lines[0]) {
+		return "", unwrapLinesInString(text, connector), nil
+	}
+
+	for i := 1; i < len(lines); i++ {
+		if isDelimiter(lines[i]) {
+			frontMatter = strings.Join(lines[1:i], "")
+			body = strings.Join(lines[i+1:], "")
+			return unwrapLinesInString(frontMatter, connector), unwrapLinesInString(body, connector), nil
+		}
+	}
+
+	message := "Front matter is not closed"
+	log.Warningf(message)
+	return "", "", errors.New(message)
+}
@@ -0,0 +1,35 @@
+package lines
+
+import "testing"
+
+func TestUnwrapWithFrontMatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		frontMatter string
+		body        string
+	}{
+		{"both wrapped", "---\ntitle: a \\\n  b\n---\nc \\\nd\n", "title: a b\n\n", "c d\n\n"},
+		{"run ends at delimiter", "---\na \\\n---\nb\n", "a \n", "b\n"},
+		{"no front matter", "a \\\nb\n", "", "a b\n\n"},
+		{"empty front matter", "---\n---\nb\n", "", "b\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frontMatter, body, err := UnwrapWithFrontMatter(test.text, "\\")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frontMatter != test.frontMatter || body != test.body {
+				t.Errorf("UnwrapWithFrontMatter(%q) = %q, %q, want %q, %q", test.text, frontMatter, body, test.frontMatter, test.body)
+			}
+		})
+	}
+
+	if _, _, err := UnwrapWithFrontMatter("---\na\n", "\\"); err == nil {
+		t.Error("unclosed front matter got no error")
+	}
+	if _, _, err := UnwrapWithFrontMatter("a\n", ""); err == nil {
+		t.Error("empty connector got no error")
+	}
+}
@@ -0,0 +1,24 @@
+package lines
+
+import (
+	"fmt"
+	"io/fs"
+
+	log "github.com/google/logger"
+)
+
+//UnwrapFS reads name from fsys (e.g. embed.FS or os.DirFS) and returns its unwrapped content
+//as UnwrapString does. No temp file is written, so read-only file systems work.
+//A file that looks binary is an ErrBinaryInput as for Unwrap
+func UnwrapFS(fsys fs.FS, name string) (string, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", name, err)
+		log.Warningf(err.Error())
+		return "", err
+	}
+	if err := (Options{}).checkText(name, b); err != nil {
+		return "", err
+	}
+	return UnwrapString(string(b)), nil
+}
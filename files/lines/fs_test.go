@@ -0,0 +1,30 @@
+package lines
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestUnwrapFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/page.tmpl": {Data: []byte("a \\\nb\nc\n")},
+		"binary.bin":          {Data: []byte("a\x00b\n")},
+	}
+
+	got, err := UnwrapFS(fsys, "templates/page.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "a b\n\nc\n" {
+		t.Errorf("UnwrapFS = %q", got)
+	}
+
+	if _, err := UnwrapFS(fsys, "missing.tmpl"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file got %v", err)
+	}
+	if _, err := UnwrapFS(fsys, "binary.bin"); !errors.Is(err, ErrBinaryInput) {
+		t.Errorf("binary file got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package lines
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+)
+
+//UnwrapGzipBytes unwraps filePath and returns the result gzip compressed,
+//unwrapped lines are compressed as they are produced without a temp file
+func UnwrapGzipBytes(filePath string) ([]byte, error) {
+	return UnwrapGzipBytesWithOptions(filePath, Options{})
+}
+
+//UnwrapGzipBytesWithOptions works as UnwrapGzipBytes, opts.GzipLevel sets the compression level
+func UnwrapGzipBytesWithOptions(filePath string, opts Options) ([]byte, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	file, err := opts.openFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	in, err := opts.textReader(filePath, file)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = opts.forFile(filePath)
+	level := opts.GzipLevel
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	var out bytes.Buffer
+	gz, _ := gzip.NewWriterLevel(&out, level) //level is validated
+
+	if err := opts.unwrapTo(in, gz, wrap); err != nil {
+		err = fmt.Errorf("Failed to unwrap file: %s: %w", filePath, err)
+		opts.logger().Warningf(err.Error())
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		err = fmt.Errorf("Failed to compress unwrapped file: %s: %w", filePath, err)
+		opts.logger().Warningf(err.Error())
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,37 @@
+package lines
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+)
+
+func TestUnwrapGzipBytes(t *testing.T) {
+	text := "a \\\nb\nc \\\n  d\n"
+	filePath := writeTemp(t, "a.tmpl", text)
+
+	for _, level := range []int{gzip.DefaultCompression, gzip.BestSpeed, gzip.BestCompression} {
+		b, err := UnwrapGzipBytesWithOptions(filePath, Options{GzipLevel: level})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		unwrapped, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := UnwrapString(text); string(unwrapped) != want {
+			t.Errorf("level %d decompressed to %q, want %q", level, unwrapped, want)
+		}
+	}
+}
+
+func TestUnwrapGzipBytesLevel(t *testing.T) {
+	if _, err := UnwrapGzipBytesWithOptions(writeTemp(t, "a.tmpl", "a\n"), Options{GzipLevel: 10}); err == nil {
+		t.Error("gzip level 10 is accepted")
+	}
+}
@@ -0,0 +1,99 @@
+package lines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//UnwrapInPlace unwraps filePath and atomically replaces it with unwrapped content: lines are
+//written to a temp file in the directory of filePath, synced and renamed over filePath.
+//Permissions and, where supported and allowed, the owner of filePath are kept, a symlink is followed
+//and stays a symlink. filePath is left as it is when anything fails
+func UnwrapInPlace(filePath string) error {
+	return UnwrapInPlaceWithOptions(filePath, Options{})
+}
+
+//UnwrapInPlaceWithOptions works as UnwrapInPlace, opts changes how lines are unwrapped
+func UnwrapInPlaceWithOptions(filePath string, opts Options) error {
+	opts = opts.forFile(filePath)
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	target, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		err = fmt.Errorf("Failed to resolve file: %s: %w", filePath, err)
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	file, err := opts.openFile(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		err = fmt.Errorf("Failed to stat file: %s: %w", target, err)
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	in, err := opts.textReader(target, file)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		err = fmt.Errorf("Failed to create a temp file next to: %s: %w", target, writeError(err))
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	err = opts.unwrapTo(in, tmpFile, wrap)
+	if err == nil {
+		err = replace(tmpFile, target, info)
+	} else {
+		tmpFile.Close()
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		err = fmt.Errorf("Failed to replace file with unwrapped text: %s: %w", target, err)
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	opts.logger().Infof("Successfuly unwrapped lines in place in %s", target)
+
+	return nil
+}
+
+//replace syncs and closes tmpFile, gives it the mode and, as far as allowed, the owner of info
+//and renames it to target. A nil info is a new target, it gets mode 0644 and the default owner. Errors match ErrWrite
+func replace(tmpFile *os.File, target string, info os.FileInfo) error {
+	err := tmpFile.Sync()
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	mode := os.FileMode(0644)
+	if info != nil {
+		mode = info.Mode().Perm()
+	}
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), mode)
+	}
+	if err == nil && info != nil {
+		chown(tmpFile.Name(), info)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), target)
+	}
+	if err != nil {
+		return writeError(err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package lines
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnwrapInPlace(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts Options
+		err  error
+		want string //content of the file afterwards
+	}{
+		{"unwrapped", "a \\\nb\n", Options{}, nil, "a b\n\n"},
+		{"nothing to unwrap", "a\nb\n", Options{}, nil, "a\nb\n"},
+		{"binary", "a \\\x00\nb\n", Options{}, ErrBinaryInput, "a \\\x00\nb\n"},
+		{"dangling", "a\nb \\\n", Options{FailOnDanglingConnector: true}, ErrDanglingConnector, "a\nb \\\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := writeTemp(t, "page.tmpl", test.text)
+
+			err := UnwrapInPlaceWithOptions(filePath, test.opts)
+			if !errors.Is(err, test.err) || (err != nil) != (test.err != nil) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if b, err := ioutil.ReadFile(filePath); err != nil || string(b) != test.want {
+				t.Errorf("file is %q, %v, want %q", b, err, test.want)
+			}
+			//the temp file next to the file is renamed or removed
+			if entries, err := os.ReadDir(filepath.Dir(filePath)); err != nil || len(entries) != 1 {
+				t.Errorf("dir has %d entries, %v, want only the file", len(entries), err)
+			}
+		})
+	}
+}
+
+func TestUnwrapInPlaceMode(t *testing.T) {
+	for _, mode := range []os.FileMode{0600, 0640, 0755} {
+		t.Run(mode.String(), func(t *testing.T) {
+			filePath := writeTemp(t, "run.sh", "echo a \\\nb\n")
+			if err := os.Chmod(filePath, mode); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := UnwrapInPlace(filePath); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != mode {
+				t.Errorf("mode is %v, want %v", info.Mode().Perm(), mode)
+			}
+		})
+	}
+}
+
+func TestUnwrapInPlaceSymlink(t *testing.T) {
+	target := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	link := filepath.Join(t.TempDir(), "link.tmpl")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("can't make a symlink: %v", err)
+	}
+
+	if err := UnwrapInPlace(link); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Lstat(link)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("link is %v, %v, want it to stay a symlink", info, err)
+	}
+	if b, err := ioutil.ReadFile(target); err != nil || string(b) != "a b\n\n" {
+		t.Errorf("target is %q, %v, want it unwrapped", b, err)
+	}
+	if entries, err := os.ReadDir(filepath.Dir(link)); err != nil || len(entries) != 1 {
+		t.Errorf("link dir has %d entries, %v, want only the link", len(entries), err)
+	}
+}
+
+func TestUnwrapInPlaceMissing(t *testing.T) {
+	if err := UnwrapInPlace(filepath.Join(t.TempDir(), "missing.tmpl")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("got %v, want an error matching os.ErrNotExist", err)
+	}
+}
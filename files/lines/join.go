@@ -0,0 +1,50 @@
+package lines
+
+import "strings"
+
+//JoinMode tells how segments of a wrap run are joined, see Options.Join
+type JoinMode int
+
+const (
+	//JoinNone trims indentation of joined lines and joins segments as they are,
+	//white space before a connector stays
+	JoinNone JoinMode = iota
+	//JoinSpace joins segments with exactly one space, white space before a connector and
+	//indentation of joined lines are trimmed. Can't be combined with Options.NewlineReplacement
+	JoinSpace
+	//JoinPreserve keeps indentation of joined lines
+	JoinPreserve
+)
+
+//IndentPolicy tells what is left of indentation of joined lines, see Options.IndentPolicy
+type IndentPolicy int
+
+const (
+	//StripAll trims indentation of joined lines, the segment butts against the previous one
+	StripAll IndentPolicy = iota
+	//KeepOne replaces indentation of a joined line with a single space,
+	//a line without indentation is joined as it is
+	KeepOne
+	//KeepOriginal keeps indentation of joined lines as it is, as JoinPreserve does
+	KeepOriginal
+)
+
+//expandTabs replaces tabs in text with spaces up to the next tab stop, every tabWidth columns
+func expandTabs(text string, tabWidth int) string {
+	if !strings.Contains(text, "\t") {
+		return text
+	}
+	var out strings.Builder
+	column := 0
+	for _, r := range text {
+		if r != '\t' {
+			out.WriteRune(r)
+			column++
+			continue
+		}
+		spaces := tabWidth - column%tabWidth
+		out.WriteString(strings.Repeat(" ", spaces))
+		column += spaces
+	}
+	return out.String()
+}
@@ -0,0 +1,90 @@
+package lines
+
+import "testing"
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		none     string
+		space    string
+		preserve string
+	}{
+		{"no white space", "a\\\nb\n", "ab\n\n", "a b\n\n", "ab\n\n"},
+		{"white space before connector", "a  \\\nb\n", "a  b\n\n", "a b\n\n", "a  b\n\n"},
+		{"indented line", "a\\\n   b\n", "ab\n\n", "a b\n\n", "a   b\n\n"},
+		{"three lines", "a\\\n b\\\n\tc\n", "abc\n\n\n", "a b c\n\n\n", "a b\tc\n\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for mode, want := range map[JoinMode]string{JoinNone: test.none, JoinSpace: test.space, JoinPreserve: test.preserve} {
+				got, err := UnwrapStringWithOptions(test.text, Options{Join: mode})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("with Join %d unwrapped %q to %q, want %q", mode, test.text, got, want)
+				}
+			}
+		})
+	}
+
+	if got := UnwrapString("a\\\nb\n"); got != "ab\n\n" {
+		t.Errorf("default join got %q, want JoinNone", got)
+	}
+}
+
+func TestExpandTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		tabWidth int
+		join     JoinMode
+		want     string
+	}{
+		{"internal tabs", "a\tb \\\n\tc\td\n", 4, JoinNone, "a   b c d\n\n"},
+		{"mixed indentation", " \t a\\\n \tb\n", 4, JoinPreserve, "     a  b\n\n"},
+		{"unjoined line", "a\tb\n", 4, JoinNone, "a\tb\n"},
+		{"off", "a\tb \\\nc\n", 0, JoinNone, "a\tb c\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{ExpandTabs: test.tabWidth, Join: test.join})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+
+	if _, err := UnwrapStringWithOptions("a\n", Options{ExpandTabs: -1}); err == nil {
+		t.Error("negative ExpandTabs got no error")
+	}
+}
+
+func TestIndentPolicy(t *testing.T) {
+	text := "key: a \\\n    b\\\nc\n"
+	tests := []struct {
+		policy IndentPolicy
+		want   string
+	}{
+		{StripAll, "key: a bc\n\n\n"},
+		{KeepOne, "key: a  bc\n\n\n"},
+		{KeepOriginal, "key: a     bc\n\n\n"},
+	}
+	for _, test := range tests {
+		got, err := UnwrapStringWithOptions(text, Options{IndentPolicy: test.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("with IndentPolicy %d unwrapped %q to %q, want %q", test.policy, text, got, test.want)
+		}
+	}
+
+	if _, err := UnwrapStringWithOptions(text, Options{IndentPolicy: KeepOne, Join: JoinSpace}); err == nil {
+		t.Error("IndentPolicy with JoinSpace got no error")
+	}
+}
@@ -0,0 +1,37 @@
+package lines
+
+import (
+	"encoding/json"
+	"fmt"
+
+	log "github.com/google/logger"
+)
+
+//UnwrapJSONLines unwraps filePath and returns its logical lines as a JSON array of strings.
+//Blank lines that Unwrap leaves in place of joined lines are not included
+func UnwrapJSONLines(filePath string) ([]byte, error) {
+	file, err := Options{}.openFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	in, err := Options{}.textReader(filePath, file)
+	if err != nil {
+		return nil, err
+	}
+
+	logicalLines := []string{}
+	u := Options{}.newUnwrapper(wrap, func(line logicalLine) error {
+		logicalLines = append(logicalLines, line.text)
+		return nil
+	})
+	if err := u.opts.forEachLineIn(in, u.add); err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		log.Warningf(err.Error())
+		return nil, err
+	}
+	u.close()
+
+	return json.Marshal(logicalLines)
+}
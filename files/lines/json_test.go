@@ -0,0 +1,34 @@
+package lines
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUnwrapJSONLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"joined lines", "a \\\nb\nc\n", []string{"a b", "c"}},
+		{"quotes and backslashes", "say \"hi\" \\\nC:\\\\dir\\x\n", []string{"say \"hi\" C:\\\\dir\\x"}},
+		{"empty file", "", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := UnwrapJSONLines(writeTemp(t, "a.txt", test.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("%s isn't a JSON array of strings: %v", b, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("UnwrapJSONLines(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
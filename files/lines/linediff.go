@@ -0,0 +1,71 @@
+package lines
+
+import (
+	"fmt"
+
+	log "github.com/google/logger"
+)
+
+//ChangeKind tells what unwrapping did to source lines, see LineChange
+type ChangeKind int
+
+const (
+	//Unchanged is a source line written as it is
+	Unchanged ChangeKind = iota
+	//Joined is a wrap run of source lines joined into one line
+	Joined
+	//Trimmed is a source line that lost trailing white space or a connector but wasn't joined
+	Trimmed
+)
+
+//LineChange is what unwrapping made of source lines First to Last (starting with 1)
+type LineChange struct {
+	First int
+	Last  int
+	//Line is the resulting logical line
+	Line string
+	Kind ChangeKind
+}
+
+//LineDiff unwraps filePath and returns a change for every logical line, in source order,
+//e.g. to comment on what unwrapping did line by line
+func LineDiff(filePath string) ([]LineChange, error) {
+	file, err := Options{}.openFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	in, err := Options{}.textReader(filePath, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []LineChange
+	var sources []string //source lines not emitted yet, the current wrap run
+	u := Options{}.newUnwrapper(wrap, func(line logicalLine) error {
+		change := LineChange{First: line.first, Last: line.first + len(line.lines) - 1, Line: line.text}
+		switch {
+		case len(line.lines) > 1:
+			change.Kind = Joined
+		case line.text != sources[0]:
+			change.Kind = Trimmed
+		}
+		changes = append(changes, change)
+		sources = sources[len(line.lines):]
+		return nil
+	})
+
+	err = u.opts.forEachLineIn(in, func(line physicalLine) error {
+		sources = append(sources, line.text)
+		return u.add(line)
+	})
+	if err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		log.Warningf(err.Error())
+		return nil, err
+	}
+	u.close()
+
+	return changes, nil
+}
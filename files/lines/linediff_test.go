@@ -0,0 +1,37 @@
+package lines
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []LineChange
+	}{
+		{"join and trim", "a \\\nb\nc \\", []LineChange{
+			{First: 1, Last: 2, Line: "a b", Kind: Joined},
+			{First: 3, Last: 3, Line: "c ", Kind: Trimmed},
+		}},
+		{"unchanged lines", "a\nb  \n", []LineChange{
+			{First: 1, Last: 1, Line: "a", Kind: Unchanged},
+			{First: 2, Last: 2, Line: "b  ", Kind: Unchanged},
+		}},
+		{"doubled connector", "a \\\\\nb\n", []LineChange{
+			{First: 1, Last: 2, Line: "a \\b", Kind: Joined},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := LineDiff(writeTemp(t, "a.txt", test.text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("LineDiff(%q) = %+v, want %+v", test.text, got, test.want)
+			}
+		})
+	}
+}
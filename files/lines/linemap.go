@@ -0,0 +1,45 @@
+package lines
+
+import "strings"
+
+//UnwrapWithMap works as UnwrapString and also returns srcLine, where srcLine[i] is the source
+//line (starting with 1) the i-th output line (starting with 0) begins on. A joined line maps
+//to the first line of its wrap run, blank lines left in place of joined lines map to the
+//lines they stand for. Unwrapping keeps line numbers, so srcLine[i] is always i+1, the map
+//lets callers not depend on that
+func UnwrapWithMap(text string) (unwrapped string, srcLine []int) {
+	opts := Options{}
+	var out strings.Builder
+	u := opts.newUnwrapper(wrap, func(line logicalLine) error {
+		for i := range line.lines {
+			srcLine = append(srcLine, line.first+i)
+		}
+		return opts.writeLine(&out, line)
+	})
+	forEachLine(text, u.add)
+	u.close()
+	return out.String(), srcLine
+}
+
+//UnwrapGroups works as UnwrapString and also returns groups, where groups[i] lists every source
+//line (starting with 1) that makes up the i-th output line (starting with 0), e.g. to highlight
+//all physical lines of a logical line. A line that isn't joined and a blank line left in place
+//of a joined line have a group of their own line only
+func UnwrapGroups(text string) (out string, groups [][]int) {
+	opts := Options{}
+	var unwrapped strings.Builder
+	u := opts.newUnwrapper(wrap, func(line logicalLine) error {
+		group := make([]int, len(line.lines))
+		for i := range line.lines {
+			group[i] = line.first + i
+		}
+		groups = append(groups, group)
+		for i := range line.lines[1:] {
+			groups = append(groups, []int{line.first + i + 1})
+		}
+		return opts.writeLine(&unwrapped, line)
+	})
+	forEachLine(text, u.add)
+	u.close()
+	return unwrapped.String(), groups
+}
@@ -0,0 +1,55 @@
+package lines
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnwrapWithMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		srcLine []int
+	}{
+		{"empty", "", nil},
+		{"not joined", "a\nb\n", []int{1, 2}},
+		{"joined", "x\na \\\nb \\\nc\nd\n", []int{1, 2, 3, 4, 5}},
+		{"without last line ending", "a \\\nb", []int{1, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unwrapped, srcLine := UnwrapWithMap(test.text)
+			if unwrapped != UnwrapString(test.text) {
+				t.Errorf("got %q, want %q", unwrapped, UnwrapString(test.text))
+			}
+			if !reflect.DeepEqual(srcLine, test.srcLine) {
+				t.Errorf("got map %v, want %v", srcLine, test.srcLine)
+			}
+		})
+	}
+}
+
+func TestUnwrapGroups(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		groups [][]int
+	}{
+		{"empty", "", nil},
+		{"not joined", "a\nb\n", [][]int{{1}, {2}}},
+		{"two lines", "a \\\nb\n", [][]int{{1, 2}, {2}}},
+		{"three lines", "x\na \\\nb \\\nc\nd\n", [][]int{{1}, {2, 3, 4}, {3}, {4}, {5}}},
+		{"two runs", "a \\\nb\nc \\\nd\n", [][]int{{1, 2}, {2}, {3, 4}, {4}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, groups := UnwrapGroups(test.text)
+			if out != UnwrapString(test.text) {
+				t.Errorf("got %q, want %q", out, UnwrapString(test.text))
+			}
+			if !reflect.DeepEqual(groups, test.groups) {
+				t.Errorf("got groups %v, want %v", groups, test.groups)
+			}
+		})
+	}
+}
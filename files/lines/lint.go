@@ -0,0 +1,81 @@
+package lines
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//longChain is how many physical lines a wrap run may have before Validate reports it
+const longChain = 20
+
+//IssueKind is the kind of a problem Validate found
+type IssueKind int
+
+const (
+	//DanglingConnector is a connector on the last line, there is no line to join it with
+	DanglingConnector IssueKind = iota
+	//ConnectorInString is a connector inside a string still open at the end of the line,
+	//it joins lines though it is likely a part of the string, see Options.QuoteAware
+	ConnectorInString
+	//LongChain is a wrap run of more than 20 physical lines
+	LongChain
+)
+
+//Issue is a problem Validate found on a source line (starting with 1)
+type Issue struct {
+	Line    int
+	Kind    IssueKind
+	Message string
+}
+
+//Validate checks text for likely mistakes in wrapping as Unwrap would unwrap it, without
+//producing any output. Returns issues in line order, an empty slice when there are none
+func Validate(text string) []Issue {
+	return ValidateWithOptions(text, Options{})
+}
+
+//ValidateWithOptions works as Validate for text unwrapped with opts, a line wraps
+//or dangles as the unwrapper decides, e.g. with Options.EscapeDoubledConnector
+func ValidateWithOptions(text string, opts Options) []Issue {
+	//a dangling connector is reported instead of failing, see close
+	opts.FailOnDanglingConnector = false
+
+	issues := []Issue{}
+	var u *unwrapper
+	var last physicalLine
+	lastNo := 0
+	u = opts.newUnwrapper(wrap, func(line logicalLine) error {
+		if len(line.lines) > longChain {
+			issues = append(issues, Issue{Line: line.first, Kind: LongChain,
+				Message: fmt.Sprintf("Line %d joins %d lines", line.first, len(line.lines))})
+		}
+		for i, physical := range line.lines {
+			if physical.connector == "" {
+				continue
+			}
+			lineNo := line.first + i
+			if inString, _ := u.scanLine(strings.TrimSuffix(physical.text, physical.connector)); inString {
+				issues = append(issues, Issue{Line: lineNo, Kind: ConnectorInString,
+					Message: fmt.Sprintf("Connector of line %d is inside a string", lineNo)})
+			}
+		}
+		if len(line.lines) > 0 {
+			last, lastNo = line.lines[len(line.lines)-1], line.first+len(line.lines)-1
+		}
+		return nil
+	})
+
+	forEachLine(text, u.add)
+	u.close()
+
+	if last.connector != "" {
+		issues = append(issues, Issue{Line: lastNo, Kind: DanglingConnector,
+			Message: fmt.Sprintf("Line %d ends with a connector but no line follows", lastNo)})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
@@ -0,0 +1,57 @@
+package lines
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		opts  Options
+		lines []int
+		kinds []IssueKind
+	}{
+		{"clean", "a \\\nb\nc\n", Options{}, []int{}, []IssueKind{}},
+		{"empty", "", Options{}, []int{}, []IssueKind{}},
+		{"dangling", "a\nb \\\n", Options{}, []int{2}, []IssueKind{DanglingConnector}},
+		{"dangling without line ending", "a\nb \\", Options{}, []int{2}, []IssueKind{DanglingConnector}},
+		{"dangling after a run", "a \\\nb \\\n", Options{}, []int{2}, []IssueKind{DanglingConnector}},
+		{"dangling with FailOnDanglingConnector", "a \\\n", Options{FailOnDanglingConnector: true}, []int{1}, []IssueKind{DanglingConnector}},
+		{"doubled connector", "C:\\dir\\\\\n", Options{}, []int{1}, []IssueKind{DanglingConnector}},
+		{"escaped connector", "C:\\dir\\\\\n", Options{EscapeDoubledConnector: true}, []int{}, []IssueKind{}},
+		{"in string", "echo \"a \\\nb\"\n", Options{}, []int{1}, []IssueKind{ConnectorInString}},
+		{"in closed string", "echo \"a\" \\\nb\n", Options{}, []int{}, []IssueKind{}},
+		{"ignored in strings", "echo \"a \\\nb\"\n", Options{Connectors: []ConnectorRule{{Connector: "\\", IgnoreInStrings: true}}}, []int{}, []IssueKind{}},
+		{"long chain", strings.Repeat("a \\\n", longChain) + "b\n", Options{}, []int{1}, []IssueKind{LongChain}},
+		{"chain at the limit", strings.Repeat("a \\\n", longChain-1) + "b\n", Options{}, []int{}, []IssueKind{}},
+		{"several", "x\necho \"a \\\nb\"\nc \\\n", Options{}, []int{2, 4}, []IssueKind{ConnectorInString, DanglingConnector}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issues := ValidateWithOptions(test.text, test.opts)
+			if issues == nil {
+				t.Fatal("issues are nil, want an empty slice")
+			}
+			lines, kinds := []int{}, []IssueKind{}
+			for _, issue := range issues {
+				lines, kinds = append(lines, issue.Line), append(kinds, issue.Kind)
+				if issue.Message == "" {
+					t.Errorf("issue of line %d has no message", issue.Line)
+				}
+			}
+			if !reflect.DeepEqual(lines, test.lines) || !reflect.DeepEqual(kinds, test.kinds) {
+				t.Errorf("got lines %v kinds %v, want lines %v kinds %v", lines, kinds, test.lines, test.kinds)
+			}
+		})
+	}
+}
+
+func TestValidateDefaultOptions(t *testing.T) {
+	text := "a \\\nb \\\n"
+	if got, want := Validate(text), ValidateWithOptions(text, Options{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,32 @@
+package lines
+
+import (
+	"fmt"
+
+	log "github.com/google/logger"
+)
+
+//Logger receives messages of functions taking Options, see Options.Logger
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+}
+
+//defaultLogger logs to github.com/google/logger, reporting the caller of its methods
+type defaultLogger struct{}
+
+func (defaultLogger) Infof(format string, args ...interface{}) {
+	log.InfoDepth(1, fmt.Sprintf(format, args...))
+}
+
+func (defaultLogger) Warningf(format string, args ...interface{}) {
+	log.WarningDepth(1, fmt.Sprintf(format, args...))
+}
+
+//logger returns Options.Logger or defaultLogger when it is nil
+func (opts Options) logger() Logger {
+	if opts.Logger != nil {
+		return opts.Logger
+	}
+	return defaultLogger{}
+}
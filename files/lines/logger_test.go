@@ -0,0 +1,68 @@
+package lines
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//recordingLogger keeps messages logged to it
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.messages = append(l.messages, "I "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Warningf(format string, args ...interface{}) {
+	l.messages = append(l.messages, "W "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) logged(prefix string) bool {
+	for _, message := range l.messages {
+		if strings.HasPrefix(message, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLogger(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	tests := []struct {
+		name    string
+		unwrap  func(opts Options) error
+		message string
+	}{
+		{"unwrap", func(opts Options) error {
+			_, cleanUp, err := UnwrapWithOptions(writeTemp(t, "a.txt", "a \\\nb\n"), opts)
+			cleanUp()
+			return err
+		}, "I Successfuly unwrapped lines to temp file"},
+		{"open failure", func(opts Options) error {
+			_, _, err := UnwrapWithOptions(missing, opts)
+			return err
+		}, "W Failed to open file"},
+		{"walk failure", func(opts Options) error {
+			_, cleanUp, err := UnwrapDir(missing, "*", opts)
+			cleanUp()
+			return err
+		}, "W Failed to walk"},
+		{"glob walk failure", func(opts Options) error {
+			_, cleanUp, err := UnwrapGlob(filepath.Join(missing, "*.txt"), opts)
+			cleanUp()
+			return err
+		}, "W Failed to walk"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			test.unwrap(Options{Logger: logger, TempDir: t.TempDir()})
+			if !logger.logged(test.message) {
+				t.Errorf("%q not logged, logged %q", test.message, logger.messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package lines
+
+import "strings"
+
+//utf8BOM is the UTF-8 byte order mark stripped from the start of the text unless
+//Options.PreserveBOM is set
+const utf8BOM = "\uFEFF"
+
+//byteOrderMarks are byte sequences kept as they are by trimming when
+//Options.OpaqueByteOrderMarks is set: the UTF-8 encoded U+FEFF (EF BB BF)
+//and raw UTF-16 byte order marks FE FF and FF FE
+var byteOrderMarks = []string{"\uFEFF", "\xFE\xFF", "\xFF\xFE"}
+
+func markPrefix(text string) string {
+	for _, mark := range byteOrderMarks {
+		if strings.HasPrefix(text, mark) {
+			return mark
+		}
+	}
+	return ""
+}
+
+func markSuffix(text string) string {
+	for _, mark := range byteOrderMarks {
+		if strings.HasSuffix(text, mark) {
+			return mark
+		}
+	}
+	return ""
+}
+
+//trimLeftKeepingMarks trims the start of text with trim around byte order marks and keeps the marks
+func trimLeftKeepingMarks(text string, trim func(string) string) string {
+	var marks strings.Builder
+	for {
+		text = trim(text)
+		mark := markPrefix(text)
+		if mark == "" {
+			return marks.String() + text
+		}
+		marks.WriteString(mark)
+		text = text[len(mark):]
+	}
+}
+
+//trimRightKeepingMarks trims the end of text with trim around byte order marks and keeps the marks
+func trimRightKeepingMarks(text string, trim func(string) string) string {
+	marks := ""
+	for {
+		text = trim(text)
+		mark := markSuffix(text)
+		if mark == "" {
+			return text + marks
+		}
+		marks = mark + marks
+		text = text[:len(text)-len(mark)]
+	}
+}
@@ -0,0 +1,31 @@
+package lines
+
+import "testing"
+
+func TestOpaqueByteOrderMarks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts Options
+		want string
+	}{
+		{"mark before the connector", "x\na \uFEFF \\\n  b\n", Options{}, "x\na \uFEFF b\n\n"},
+		{"mark starting a joined line", "x\na \\\n  \uFEFF  b\n", Options{}, "x\na \uFEFFb\n\n"},
+		{"UTF-16 marks", "x\na \xFE\xFF \\\n \xFF\xFE b\n", Options{}, "x\na \xFE\xFF \xFF\xFEb\n\n"},
+		{"space join", "x\na \uFEFF \\\n  b\n", Options{Join: JoinSpace}, "x\na\uFEFF b\n\n"},
+		{"trailing white space", "x\na \uFEFF \t\n", Options{TrimAllTrailingWhitespace: true}, "x\na\uFEFF\n"},
+		{"unjoined line", "x\na \uFEFF\n", Options{}, "x\na \uFEFF\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.OpaqueByteOrderMarks = true
+			got, err := UnwrapStringWithOptions(test.text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
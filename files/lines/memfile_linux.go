@@ -0,0 +1,19 @@
+//go:build linux
+// +build linux
+
+package lines
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+//memFile creates an anonymous file that lives in memory only
+func memFile(name string) (*os.File, error) {
+	fd, err := unix.MemfdCreate(name, unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), name), nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package lines
+
+import (
+	"errors"
+	"os"
+)
+
+//memFile is only supported on Linux, callers fall back to a temp file
+func memFile(name string) (*os.File, error) {
+	return nil, errors.New("memfd is not supported on this platform")
+}
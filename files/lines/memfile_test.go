@@ -0,0 +1,34 @@
+package lines
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMemFile(t *testing.T) {
+	newFilePath, cleanUp, err := UnwrapWithOptions(writeTemp(t, "a.txt", "a \\\nb\n"), Options{MemFile: true, TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+
+	//off Linux the temp file is a regular one
+	if inMemory := strings.HasPrefix(newFilePath, "/proc/self/fd/"); inMemory != (runtime.GOOS == "linux") {
+		t.Errorf("unwrapped to %s on %s", newFilePath, runtime.GOOS)
+	}
+	b, err := ioutil.ReadFile(newFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a b\n\n" {
+		t.Errorf("unwrapped to %q", b)
+	}
+
+	cleanUp()
+	if _, err := os.Stat(newFilePath); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after clean up: %v", newFilePath, err)
+	}
+}
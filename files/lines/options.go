@@ -0,0 +1,455 @@
+package lines
+
+import (
+	"compress/gzip"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//Options changes how UnwrapWithOptions processes a file.
+//Zero value behaves exactly like Unwrap
+type Options struct {
+	//DeterministicTempName names the temp file after a hash of the source path, the source
+	//and the options instead of a random name, so unwrapping the same input again returns
+	//the same path and reuses the file without unwrapping if it is still there
+	DeterministicTempName bool
+
+	//ForceLineEnding rewrites every line ending of the output to the given one,
+	//"\n" or "\r\n", no matter what the input used. Empty leaves output as unwrapped
+	ForceLineEnding string
+
+	//Remove deletes temp files when cleanUp is called, os.Remove by default.
+	//Set it when temp files live on a filesystem abstraction os.Remove can't reach
+	Remove func(name string) error
+
+	//MaxCollapseRun leaves wrap runs of more than MaxCollapseRun physical lines wrapped
+	//as they are and only joins shorter ones. 0 joins every run
+	MaxCollapseRun int
+
+	//MemFile keeps the unwrapped content in an anonymous in-memory file (memfd) on Linux
+	//instead of a temp file on disk. The returned path is /proc/self/fd/N and stays valid
+	//until cleanUp is called. Other platforms fall back to a regular temp file
+	MemFile bool
+
+	//ErrorStrategy tells UnwrapFiles and UnwrapDir what to do when a file fails,
+	//CollectErrors by default
+	ErrorStrategy ErrorStrategy
+
+	//MaxErrors is how many failed files CollectErrorsUpTo collects before it stops
+	MaxErrors int
+
+	//MaxFiles makes UnwrapFiles and UnwrapDir fail with ErrTooManyFiles before unwrapping
+	//anything when more files are given or match. 0 means no limit
+	MaxFiles int
+
+	//Progress is called by UnwrapFiles and UnwrapDir after every file, failed or not,
+	//with the number of files done so far and the number of all files. Files are
+	//unwrapped one by one, so calls never overlap
+	Progress func(done, total int)
+
+	//PerFileTimeout fails a file UnwrapFiles and UnwrapDir unwrap for longer than PerFileTimeout
+	//with an error matching context.DeadlineExceeded, other files are unwrapped as
+	//ErrorStrategy says. A read blocked past the timeout is cut off for files supporting read
+	//deadlines (e.g. pipes), other files are checked between reads. 0 means no timeout
+	PerFileTimeout time.Duration
+
+	//OpaqueByteOrderMarks keeps byte order marks anywhere in a line untouched by whitespace
+	//trimming. Whitespace on both sides of a mark is trimmed as if the mark wasn't there,
+	//the mark itself always stays. See byteOrderMarks for the exact byte sequences
+	OpaqueByteOrderMarks bool
+
+	//GzipLevel is the compression level used by UnwrapGzipBytesWithOptions,
+	//one of compress/gzip levels. 0 means gzip.DefaultCompression
+	GzipLevel int
+
+	//ParagraphMode treats a line holding only the connector as a paragraph joint that
+	//joins the line before it and the next non-blank line with a space, see paragraph.go
+	ParagraphMode bool
+
+	//ValidateUTF8 fails unwrapping with the number of the first source line
+	//of a logical line that isn't valid UTF-8
+	ValidateUTF8 bool
+
+	//RecordTo receives a trace of every decision the unwrapper makes as JSON lines
+	//of TraceEvent, attach it to bug reports and check it with Replay
+	RecordTo io.Writer
+
+	//UnicodeWhitespace trims all Unicode white space (unicode.IsSpace) around joins and
+	//line ends instead of spaces, tabs and line endings only. Note that it trims
+	//no-break spaces (U+00A0) too
+	UnicodeWhitespace bool
+
+	//CommentPrefix starts a line comment, a connector inside a comment doesn't join lines.
+	//Empty disables comment awareness
+	CommentPrefix string
+
+	//CommentStyles maps file extensions to CommentPrefix used for files with that extension
+	//when CommentPrefix is not set, e.g. DefaultCommentStyles. Other extensions get no
+	//comment awareness. Nil disables picking comment prefix by extension
+	CommentStyles map[string]string
+
+	//WarnOnTokenMerge logs a warning when a join glues two letters or digits together,
+	//e.g. "foo\\" followed by "bar" becomes "foobar", usually a space is missing before the connector
+	WarnOnTokenMerge bool
+
+	//Engine is the template engine the text is for: "jinja", "handlebars" or
+	//"text/template". Jinja and Handlebars tags and text/template comments ({{/* */}})
+	//may span lines, so a connector at the end of a line with a tag still open is a part
+	//of the tag and doesn't join lines. Empty doesn't look for tags
+	Engine string
+
+	//SpillThreshold moves a line being joined to a temp file on disk once it grows
+	//over SpillThreshold bytes and streams it from there to the output, bounding memory
+	//for huge joined lines. When no temp file can be created the line stays in memory.
+	//Can't be combined with MaxCollapseRun, ParagraphMode and ContinuationPrefix. 0 keeps all in memory
+	SpillThreshold int64
+
+	//OnStripConnector is called with the text of a wrapped line before its connector,
+	//the returned text is joined instead, e.g. to drop a trailing comma at wrap points
+	OnStripConnector func(beforeConnector string) string
+
+	//ForceIndent replaces leading white space of every joined line with exactly ForceIndent
+	//spaces, lines spilled by SpillThreshold keep their indentation. 0 keeps indentation
+	ForceIndent int
+
+	//ForceIndentAll applies ForceIndent to lines that weren't joined too
+	ForceIndentAll bool
+
+	//NewlineReplacement is put in place of every line break consumed by a join,
+	//e.g. `\n` for JSON strings or "; " for statements. Empty joins segments as they are
+	NewlineReplacement string
+
+	//StartMarker leaves all lines up to the first line holding only StartMarker as they are
+	//(e.g. a license header) and unwraps lines after it. Empty unwraps all lines
+	StartMarker string
+
+	//ConsumeStartMarker replaces the StartMarker line with an empty line
+	ConsumeStartMarker bool
+
+	//Connectors replaces the connector with a set of connectors, each with its own
+	//comment and string awareness. The longest connector matching the end of a line
+	//wins, see ConnectorRule
+	Connectors []ConnectorRule
+
+	//Quotes are characters that quote strings for ConnectorRule.IgnoreInStrings,
+	//"\"'" by default
+	Quotes string
+
+	//Hash is the hash algorithm UnwrapVerifyWithOptions checks content with, crypto.SHA256 by
+	//default. Other algorithms must be linked in by importing their package (e.g. crypto/sha512)
+	Hash crypto.Hash
+
+	//Logger receives messages instead of github.com/google/logger, e.g. to route them to
+	//another logging package or to drop them. Functions not taking Options always log
+	//to github.com/google/logger
+	Logger Logger
+
+	//BalanceParens joins a line leaving parentheses open with the next lines until they are
+	//balanced, with no connector needed, see parens.go
+	BalanceParens bool
+
+	//LineDirectives writes a line directive of the given format before every logical line
+	//pointing at the source line it starts on, so compilers report errors against the
+	//source. Empty writes no directives
+	LineDirectives LineDirective
+
+	//LineDirectiveFile is the file name written in line directives,
+	//functions unwrapping a file use its path when it is empty
+	LineDirectiveFile string
+
+	//MaxPhysicalLineLength fails unwrapping with ErrLineTooLong when a source line is longer
+	//than MaxPhysicalLineLength bytes, line ending not counted. Reading a file stops as soon
+	//as the limit is crossed, so memory stays bounded for untrusted input. 0 means no limit
+	MaxPhysicalLineLength int
+
+	//OutputEncoder wraps the writer unwrapped text is written to, e.g. with base64.NewEncoder,
+	//and is closed to flush when all is written. With GzipLevel the encoded text is compressed.
+	//Nil writes unwrapped text as it is
+	OutputEncoder func(w io.Writer) io.WriteCloser
+
+	//TrimAllTrailingWhitespace trims trailing white space off every output line, also off
+	//lines before StartMarker and off joined lines ending with white space left before a
+	//stripped connector (e.g. "a \\" followed by an empty line). Source lines are always
+	//trimmed before looking for a connector, but that alone leaves those lines untrimmed.
+	//Lines spilled by SpillThreshold are not trimmed
+	TrimAllTrailingWhitespace bool
+
+	//ContinuationPrefix makes a join need both markers: a line ending with a connector only
+	//joins the next line when it starts with ContinuationPrefix after its indentation.
+	//The prefix is stripped from joined lines, white space after it is kept. When the next
+	//line doesn't start with it, or there is no next line, the connector stays in place.
+	//Can't be combined with SpillThreshold. Empty joins on the connector alone
+	ContinuationPrefix string
+
+	//PreserveLineEndings writes every line ending as it is in the source, "\r\n" or "\n",
+	//instead of "\n". Blank lines left in place of joined lines keep the endings of the
+	//lines they stand for. Can't be combined with ForceLineEnding
+	PreserveLineEndings bool
+
+	//DominantLineEnding writes every line ending as the one most lines of the source end with,
+	//"\r\n" or "\n", decided by the first 8000 bytes of a file or a stream.
+	//Can't be combined with PreserveLineEndings or ForceLineEnding
+	DominantLineEnding bool
+
+	//EscapeDoubledConnector makes a doubled connector at the end of a line a literal connector
+	//that doesn't wrap, e.g. a line ending with "\\\\" ends with "\\" and isn't joined, one
+	//ending with "\\\\\\" ends with "\\" and is joined. Connectors ignored in comments or
+	//strings are left as they are. Off by default: the literal connector left at the end of
+	//a line wraps when the unwrapped text is unwrapped again, so unwrapping with the escape
+	//isn't idempotent, see IsUnwrapped
+	EscapeDoubledConnector bool
+
+	//Join is how segments of a wrap run are joined, JoinNone by default
+	Join JoinMode
+
+	//IndentPolicy is what is left of indentation of joined lines, StripAll by default.
+	//It only applies with JoinNone, JoinSpace and JoinPreserve already decide on indentation
+	//so combining them with another policy is an error
+	IndentPolicy IndentPolicy
+
+	//FailOnDanglingConnector fails unwrapping with ErrDanglingConnector when the last line
+	//ends with a connector instead of trimming the connector
+	FailOnDanglingConnector bool
+
+	//QuoteAware leaves a connector inside a string quoted with one of Quotes that is still
+	//open at the end of the line as it is, e.g. `x := "foo\` isn't joined. Connectors
+	//set by Connectors use ConnectorRule.IgnoreInStrings instead. With Engine "text/template"
+	//connectors in template comments are left as they are too
+	QuoteAware bool
+
+	//TempDir is the directory temp files are created in, it must exist and be writable.
+	//Empty uses the default directory for temp files (os.TempDir)
+	TempDir string
+
+	//TempNextToSource creates temp files in the directory of the source file instead of
+	//TempDir, e.g. so relative includes of templates still resolve. Can't be combined with TempDir.
+	//Spill files of text without a source file go to the default temp directory
+	TempNextToSource bool
+
+	//TempPattern returns the name pattern of the temp file for filePath as ioutil.TempFile takes
+	//it, the last "*" is replaced with a random string. By default it is the base name of
+	//filePath with "*" before its extension, e.g. "page*.tmpl" for "templates/page.tmpl"
+	TempPattern func(filePath string) string
+
+	//Cleaner gets clean ups of temp files created with these options,
+	//so they are removed by Cleaner.Close too
+	Cleaner *Cleaner
+
+	//MaxContinuations fails unwrapping with ErrTooManyContinuations when a wrap run joins more
+	//than MaxContinuations physical lines into one, unlike MaxCollapseRun that leaves such
+	//runs wrapped. 0 means no limit
+	MaxContinuations int
+
+	//ExpandTabs replaces tabs left in joined lines with spaces up to the next tab stop,
+	//every ExpandTabs columns. Lines that weren't joined and lines spilled by SpillThreshold
+	//keep their tabs. 0 leaves tabs as they are
+	ExpandTabs int
+
+	//PreserveBOM keeps a UTF-8 byte order mark at the start of the text,
+	//by default it is stripped
+	PreserveBOM bool
+
+	//TrimUnjoinedLines trims trailing white space off lines that aren't joined too, as
+	//unwrapping did before. By default such lines are written as they are in the source
+	TrimUnjoinedLines bool
+
+	//AllowBinary unwraps files that look binary (a NUL byte or mostly invalid UTF-8 in
+	//their first bytes). By default unwrapping a file path fails with ErrBinaryInput,
+	//text passed as a string or a stream is never checked
+	AllowBinary bool
+
+	//SkipPrefix leaves lines starting with it after indentation as they are, e.g. "# noexpand".
+	//Such a line is never joined: it ends the wrap run before it and doesn't wrap itself
+	SkipPrefix string
+
+	//MaxOutLine breaks output lines longer than MaxOutLine bytes again at white space as
+	//Rewrap does, with the first connector (a backslash by default) ending broken lines.
+	//Line numbers are kept as long as a line isn't broken into more lines than were joined
+	//into it. A token longer than MaxOutLine isn't broken. Unwrapping the result with
+	//JoinNone and StripAll gives the same lines, a connector in a comment isn't unwrapped
+	//though. 0 doesn't break lines
+	MaxOutLine int
+
+	filePath string //the source file set by forFile, see LineError
+}
+
+//DefaultCommentStyles maps extensions of common file types to their line comment prefix
+var DefaultCommentStyles = map[string]string{
+	".go":   "//",
+	".js":   "//",
+	".ts":   "//",
+	".c":    "//",
+	".cpp":  "//",
+	".java": "//",
+	".sh":   "#",
+	".py":   "#",
+	".rb":   "#",
+	".yaml": "#",
+	".yml":  "#",
+	".toml": "#",
+	".sql":  "--",
+	".lua":  "--",
+}
+
+//forFile returns opts with settings that depend on filePath picked
+func (opts Options) forFile(filePath string) Options {
+	if opts.CommentPrefix == "" && opts.CommentStyles != nil {
+		opts.CommentPrefix = opts.CommentStyles[filepath.Ext(filePath)]
+	}
+	if opts.LineDirectiveFile == "" {
+		opts.LineDirectiveFile = filePath
+	}
+	opts.filePath = filePath
+	return opts
+}
+
+//dominantLineEnding sets ForceLineEnding to the line ending most lines of head end with
+//when Options.DominantLineEnding is set
+func (opts Options) dominantLineEnding(head string) Options {
+	if !opts.DominantLineEnding {
+		return opts
+	}
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	lf := strings.Count(head, "\n")
+	if lf == 0 {
+		return opts
+	}
+	crlf := strings.Count(head, "\r\n")
+	opts.ForceLineEnding = "\n"
+	if crlf > lf-crlf {
+		opts.ForceLineEnding = "\r\n"
+	}
+	return opts
+}
+
+func (opts Options) remove(name string) error {
+	if opts.Remove != nil {
+		return opts.Remove(name)
+	}
+	return os.Remove(name)
+}
+
+func (opts Options) validate() error {
+	if opts.GzipLevel < gzip.HuffmanOnly || opts.GzipLevel > gzip.BestCompression {
+		message := fmt.Sprintf("Unsupported gzip level: %d", opts.GzipLevel)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.TempNextToSource && opts.TempDir != "" {
+		message := "TempNextToSource can't be combined with TempDir"
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.MaxOutLine < 0 {
+		message := fmt.Sprintf("Maximum output line length must not be negative: %d", opts.MaxOutLine)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.ExpandTabs < 0 {
+		message := fmt.Sprintf("Tab width must not be negative: %d", opts.ExpandTabs)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.ForceIndent < 0 {
+		message := fmt.Sprintf("Indentation must not be negative: %d", opts.ForceIndent)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.SpillThreshold > 0 && (opts.MaxCollapseRun > 0 || opts.ParagraphMode || opts.ContinuationPrefix != "") {
+		message := "SpillThreshold can't be combined with MaxCollapseRun, ParagraphMode or ContinuationPrefix"
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if err := opts.validateConnectors(); err != nil {
+		return err
+	}
+
+	if _, ok := engineTags[opts.Engine]; !ok {
+		message := fmt.Sprintf("Unsupported template engine: %s", opts.Engine)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	switch opts.LineDirectives {
+	case "", CLineDirective, GoLineDirective:
+	default:
+		message := fmt.Sprintf("Unsupported line directive: %s", opts.LineDirectives)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.Join == JoinSpace && opts.NewlineReplacement != "" {
+		message := "JoinSpace can't be combined with NewlineReplacement"
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.IndentPolicy < StripAll || opts.IndentPolicy > KeepOriginal {
+		message := fmt.Sprintf("Unsupported indent policy: %d", opts.IndentPolicy)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.Join != JoinNone && opts.IndentPolicy != StripAll {
+		message := "IndentPolicy can't be combined with JoinSpace or JoinPreserve"
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.PreserveLineEndings && opts.ForceLineEnding != "" {
+		message := "PreserveLineEndings can't be combined with ForceLineEnding"
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	if opts.DominantLineEnding && (opts.PreserveLineEndings || opts.ForceLineEnding != "") {
+		message := "DominantLineEnding can't be combined with PreserveLineEndings or ForceLineEnding"
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+
+	switch opts.ForceLineEnding {
+	case "", "\n", "\r\n":
+	default:
+		message := fmt.Sprintf("Unsupported line ending: %q", opts.ForceLineEnding)
+		opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+	return nil
+}
+
+//unwrapText unwraps text and applies opts to the result
+func (opts Options) unwrapText(text string) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+
+	return opts.unwrapLines(text, wrap)
+}
+
+//cacheKey returns opts without what doesn't change unwrapped text, temp files of unwraps with
+//equal keys of the same source are the same, see Options.DeterministicTempName.
+//Reports false when callbacks changing unwrapped text are set, they can't be compared
+func (opts Options) cacheKey() (key Options, ok bool) {
+	ok = opts.OnStripConnector == nil && opts.OutputEncoder == nil
+	key = opts
+	key.Remove, key.Progress, key.RecordTo, key.Logger, key.Cleaner = nil, nil, nil, nil, nil
+	key.OnStripConnector, key.OutputEncoder, key.TempPattern = nil, nil, nil
+	key.TempDir, key.TempNextToSource, key.MemFile, key.filePath = "", false, false, ""
+	key.ErrorStrategy, key.MaxErrors, key.MaxFiles, key.PerFileTimeout = 0, 0, 0, 0
+	return key, ok
+}
@@ -0,0 +1,167 @@
+package lines
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestForceLineEnding(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		force string
+		want  string
+	}{
+		{"CRLF on LF", "a \\\nb\nc\n", "\r\n", "a b\r\n\r\nc\r\n"},
+		{"LF on CRLF", "a \\\r\nb\r\nc\r\n", "\n", "a b\n\nc\n"},
+		{"mixed", "a\r\nb \\\nc\r\n", "\r\n", "a\r\nb c\r\n\r\n"},
+		{"no line ending at the end", "a\nb", "\r\n", "a\r\nb"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{ForceLineEnding: test.force})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestForceLineEndingExclusive(t *testing.T) {
+	for _, opts := range []Options{
+		{ForceLineEnding: "\n", PreserveLineEndings: true},
+		{ForceLineEnding: "\n", DominantLineEnding: true},
+		{ForceLineEnding: "\r"},
+	} {
+		if _, err := UnwrapStringWithOptions("a\n", opts); err == nil {
+			t.Errorf("%+v is accepted", opts)
+		}
+	}
+}
+
+func TestMaxCollapseRun(t *testing.T) {
+	long := strings.Repeat("x \\\n", 9) + "x\n"
+	text := "a \\\nb\n" + long
+	got, err := UnwrapStringWithOptions(text, Options{MaxCollapseRun: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a b\n\n" + long; got != want {
+		t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", text, got, want)
+	}
+}
+
+func TestValidateUTF8(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		line int //of the error, 0 for none
+	}{
+		{"valid", "a \\\nb é\n", 0},
+		{"invalid byte", "a\nb \xff\n", 2},
+		{"invalid joined line", "a\nb \\\nc \\\n\xc3\n", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := UnwrapStringWithOptions(test.text, Options{ValidateUTF8: true})
+			var lineErr *LineError
+			switch {
+			case test.line == 0 && err != nil:
+				t.Errorf("got %v", err)
+			case test.line > 0 && !errors.As(err, &lineErr):
+				t.Errorf("got %v, want a LineError", err)
+			case test.line > 0 && (lineErr.Line != test.line || !errors.Is(err, ErrInvalidUTF8)):
+				t.Errorf("got %v, want %v at line %d", err, ErrInvalidUTF8, test.line)
+			}
+		})
+	}
+}
+
+func TestTempFileLocation(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		nextTo  bool   //the temp file is next to the source
+		pattern string //the base name of the temp file matches it
+		fails   bool
+	}{
+		{"default", Options{}, false, "page*.tmpl", false},
+		{"next to source", Options{TempNextToSource: true}, true, "page*.tmpl", false},
+		{"pattern", Options{TempNextToSource: true, TempPattern: func(filePath string) string {
+			return "." + filepath.Base(filePath) + ".unwrapped-*"
+		}}, true, ".page.tmpl.unwrapped-*", false},
+		{"next to source with TempDir", Options{TempNextToSource: true, TempDir: os.TempDir()}, false, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+			newFilePath, cleanUp, err := UnwrapWithOptions(filePath, test.opts)
+			defer cleanUp()
+			if test.fails {
+				if err == nil {
+					t.Error("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if nextTo := filepath.Dir(newFilePath) == filepath.Dir(filePath); nextTo != test.nextTo {
+				t.Errorf("temp file %s next to the source: %t, want %t", newFilePath, nextTo, test.nextTo)
+			}
+			if matched, _ := filepath.Match(test.pattern, filepath.Base(newFilePath)); !matched {
+				t.Errorf("temp file %s doesn't match %q", newFilePath, test.pattern)
+			}
+		})
+	}
+}
+
+func TestMaxContinuations(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		line int //line of the LineError, 0 for none
+	}{
+		{"no limit", "a \\\nb \\\nc \\\nd\n", 0, 0},
+		{"at the limit", "a \\\nb \\\nc\n", 3, 0},
+		{"over the limit", "a \\\nb \\\nc \\\nd\n", 3, 1},
+		{"second run over the limit", "x\ny \\\nz\na \\\nb \\\nc\n", 2, 4},
+		{"dangling run over the limit", "x\na \\\nb \\\n", 1, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := UnwrapStringWithOptions(test.text, Options{MaxContinuations: test.max})
+			if test.line == 0 {
+				if err != nil {
+					t.Errorf("got %v", err)
+				}
+				return
+			}
+			var lineErr *LineError
+			if !errors.As(err, &lineErr) || !errors.Is(err, ErrTooManyContinuations) {
+				t.Fatalf("got %v, want a LineError with ErrTooManyContinuations", err)
+			}
+			if lineErr.Line != test.line {
+				t.Errorf("error at line %d, want %d", lineErr.Line, test.line)
+			}
+		})
+	}
+}
+
+func TestMaxContinuationsFile(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "x\na \\\nb \\\nc\n")
+	_, cleanUp, err := UnwrapWithOptions(filePath, Options{MaxContinuations: 2, TempDir: t.TempDir()})
+	defer cleanUp()
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.File != filePath || lineErr.Line != 2 {
+		t.Errorf("got %v, want a LineError at %s:2", err, filePath)
+	}
+}
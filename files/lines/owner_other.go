@@ -0,0 +1,10 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package lines
+
+import "os"
+
+//chown is only supported on Unix, elsewhere the replaced file gets the default owner
+func chown(name string, info os.FileInfo) {
+}
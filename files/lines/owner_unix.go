@@ -0,0 +1,22 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package lines
+
+import (
+	"os"
+	"syscall"
+)
+
+//chown gives file name the owner and group of info as far as allowed: only root may give
+//a file to another user, the owner may give it to a group they belong to, else the
+//file keeps the default owner
+func chown(name string, info os.FileInfo) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	if os.Chown(name, int(stat.Uid), int(stat.Gid)) != nil {
+		os.Chown(name, -1, int(stat.Gid))
+	}
+}
@@ -0,0 +1,39 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package lines
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+//owner returns the owner and group of filePath
+func owner(t *testing.T, filePath string) (uid int, gid int) {
+	t.Helper()
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := info.Sys().(*syscall.Stat_t)
+	return int(stat.Uid), int(stat.Gid)
+}
+
+func TestUnwrapInPlaceOwner(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	if os.Getuid() == 0 {
+		//root may give the file to any user, it is given back when replaced
+		if err := os.Chown(filePath, 65534, 65534); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uid, gid := owner(t, filePath)
+
+	if err := UnwrapInPlace(filePath); err != nil {
+		t.Fatal(err)
+	}
+	if gotUID, gotGID := owner(t, filePath); gotUID != uid || gotGID != gid {
+		t.Errorf("owner is %d:%d, want %d:%d", gotUID, gotGID, uid, gid)
+	}
+}
@@ -0,0 +1,54 @@
+package lines
+
+//In ParagraphMode a line holding nothing but the connector is a paragraph joint:
+//it joins the logical line before it with the next non-blank logical line after it,
+//separated by a single space. The joint and blank lines between it and the next
+//non-blank line become blank padding lines as joined lines do.
+//A joint on the first line or left open at the end of the text joins nothing.
+//To find the line before a joint the unwrapper holds every logical line until the next one completes
+
+//joinParagraph takes a paragraph joint line
+func (u *unwrapper) joinParagraph(joint physicalLine) {
+	u.held.lines = append(u.held.lines, joint)
+	u.joinParagraphs = true
+}
+
+//hold emits the held logical line and holds line instead,
+//or joins line into the held one after a paragraph joint
+func (u *unwrapper) hold(line logicalLine) error {
+	if u.held == nil {
+		u.held = &line
+		return nil
+	}
+
+	if u.joinParagraphs {
+		u.held.lines = append(u.held.lines, line.lines...)
+		text := u.trimLeft(u.trimRight(line.text))
+		if text == "" {
+			return nil
+		}
+		u.held.text = u.trimRight(u.held.text)
+		if u.held.text != "" {
+			u.held.text += " "
+		}
+		u.held.text += text
+		u.joinParagraphs = false
+		return nil
+	}
+
+	if err := u.emit(*u.held); err != nil {
+		return err
+	}
+	u.held = &line
+	return nil
+}
+
+//release emits the held logical line
+func (u *unwrapper) release() error {
+	if u.held == nil {
+		return nil
+	}
+	held := u.held
+	u.held, u.joinParagraphs = nil, false
+	return u.emit(*held)
+}
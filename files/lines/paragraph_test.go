@@ -0,0 +1,29 @@
+package lines
+
+import "testing"
+
+func TestParagraphMode(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"joint", "para one\n\\\npara two\n", "para one para two\n\n\n"},
+		{"indented next paragraph", "para one\n\\\n  para two\n", "para one para two\n\n\n"},
+		{"blank lines after joint", "para one\n\\\n\n\tpara two\n", "para one para two\n\n\n\n"},
+		{"no joint", "para one\n\npara two\n", "para one\n\npara two\n"},
+		{"connector on the first line", "\\\npara\n", "para\n\n"},
+		{"joint at the end", "para\n\\\n", "para\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{ParagraphMode: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package lines
+
+import "strings"
+
+//BalanceParens joins lines implicitly, without a connector: a line that leaves more
+//parentheses open than it closes is joined with the next lines until all are closed.
+//Lines joined this way are separated with NewlineReplacement, or a single space when it
+//is empty. Parentheses inside line comments are ignored when CommentPrefix is set and
+//inside strings when Quotes is set. Unmatched closing parentheses never make the depth
+//negative. A line ending with a connector is joined by the connector as usual
+
+//countParens adds open and closed parentheses of text to depth and returns the new depth
+func (u *unwrapper) countParens(text string, depth int) int {
+	var quote rune
+	escaped := false
+	for i, r := range text {
+		switch {
+		case quote != 0 && escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case u.opts.CommentPrefix != "" && strings.HasPrefix(text[i:], u.opts.CommentPrefix):
+			return depth
+		case u.opts.Quotes != "" && strings.ContainsRune(u.opts.Quotes, r):
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		}
+	}
+	return depth
+}
@@ -0,0 +1,32 @@
+package lines
+
+import "testing"
+
+func TestBalanceParens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts Options
+		want string
+	}{
+		{"unbalanced expression", "(define (f x)\n  (+ x\n     1))\n(f 2)\n", Options{}, "(define (f x) (+ x 1))\n\n\n(f 2)\n"},
+		{"balanced lines", "(a)\n(b)\n", Options{}, "(a)\n(b)\n"},
+		{"unmatched closing", "a)\n(b\nc)\n", Options{}, "a)\n(b c)\n\n"},
+		{"connector", "(a \\\nb\nc)\n", Options{}, "(a b c)\n\n\n"},
+		{"in a comment", "a ; (\nb\n", Options{CommentPrefix: ";"}, "a ; (\nb\n"},
+		{"in a string", "a \"(\"\nb\n", Options{Quotes: "\""}, "a \"(\"\nb\n"},
+		{"newline replacement", "(a\nb)\n", Options{NewlineReplacement: "; "}, "(a; b)\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.BalanceParens = true
+			got, err := UnwrapStringWithOptions(test.text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package lines
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/google/logger"
+)
+
+//patchContext is the number of unchanged lines around changes in a patch
+const patchContext = 3
+
+//UnwrapPatch returns a unified diff that turns filePath into its unwrapped content,
+//apply it to the original file with patch(1). Headers of the patch use filePath.
+//The patch is empty when there is nothing to unwrap
+func UnwrapPatch(filePath string) ([]byte, error) {
+	text, err := Options{}.readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	unwrapped, err := Options{}.unwrapText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	before := strings.SplitAfter(text, "\n")
+	after := strings.SplitAfter(unwrapped, "\n")
+	for len(after) < len(before) && !strings.HasSuffix(unwrapped, "\n") {
+		//a joined last line without a line ending has no empty lines after it
+		after = append(after, "")
+	}
+	if len(before) != len(after) {
+		//unwrapping keeps line numbers, every source line has its output line
+		message := fmt.Sprintf("Unwrapped lines don't match source lines of: %s", filePath)
+		log.Warningf(message)
+		return nil, errors.New(message)
+	}
+
+	var patch strings.Builder
+	for n := 0; n < len(before); n++ {
+		if before[n] == after[n] {
+			continue
+		}
+		if patch.Len() == 0 {
+			fmt.Fprintf(&patch, "--- %s\n+++ %s\n", filePath, filePath)
+		}
+
+		//hunk goes from the first changed line to the last one closer than 2*patchContext to the next change
+		first, last := n, n
+		for next := n + 1; next < len(before) && next <= last+2*patchContext; next++ {
+			if before[next] != after[next] {
+				last = next
+			}
+		}
+
+		start, end := first-patchContext, last+patchContext
+		if start < 0 {
+			start = 0
+		}
+		if end > len(before)-1 {
+			end = len(before) - 1
+		}
+		fmt.Fprintf(&patch, "@@ -%s +%s @@\n", hunkRange(before[start:end+1], start), hunkRange(after[start:end+1], start))
+
+		for i := start; i <= end; {
+			if before[i] == after[i] {
+				writePatchLine(&patch, " ", before[i])
+				i++
+				continue
+			}
+			changed := i
+			for changed <= end && before[changed] != after[changed] {
+				changed++
+			}
+			for _, line := range before[i:changed] {
+				writePatchLine(&patch, "-", line)
+			}
+			for _, line := range after[i:changed] {
+				writePatchLine(&patch, "+", line)
+			}
+			i = changed
+		}
+
+		n = last
+	}
+
+	return []byte(patch.String()), nil
+}
+
+//hunkRange returns start and count of lines in a hunk, an empty line is
+//what strings.SplitAfter leaves after the last line ending, not a line
+func hunkRange(lines []string, start int) string {
+	count := 0
+	for _, line := range lines {
+		if line != "" {
+			count++
+		}
+	}
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writePatchLine(patch *strings.Builder, prefix string, line string) {
+	if line == "" {
+		return
+	}
+	patch.WriteString(prefix)
+	patch.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		patch.WriteString("\n\\ No newline at end of file\n")
+	}
+}
@@ -0,0 +1,59 @@
+package lines
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestUnwrapPatch(t *testing.T) {
+	patchPath, err := exec.LookPath("patch")
+	if err != nil {
+		t.Skip("patch is not installed")
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"one join", "a \\\nb\n"},
+		{"joins far apart", "a \\\nb\n" + strings.Repeat("c\n", 10) + "d \\\ne\n"},
+		{"joins close together", "a \\\nb\nc\nd \\\ne\n"},
+		{"no trailing newline", "a\nb \\\nc"},
+		{"joined last lines", "a\nb \\\nc \\\nd"},
+		{"nothing to unwrap", "a\nb\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := writeTemp(t, "page.tmpl", test.text)
+			patch, err := UnwrapPatch(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if UnwrapString(test.text) == test.text {
+				if len(patch) > 0 {
+					t.Errorf("nothing to unwrap got a patch:\n%s", patch)
+				}
+				return
+			}
+			if !bytes.HasPrefix(patch, []byte("--- "+filePath+"\n+++ "+filePath+"\n")) {
+				t.Fatalf("patch headers don't use %s:\n%s", filePath, patch)
+			}
+
+			cmd := exec.Command(patchPath, "-s", filePath)
+			cmd.Stdin = bytes.NewReader(patch)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("applying patch failed: %v: %s\n%s", err, out, patch)
+			}
+			b, err := ioutil.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := UnwrapString(test.text); string(b) != want {
+				t.Errorf("patched file has %q, want %q", b, want)
+			}
+		})
+	}
+}
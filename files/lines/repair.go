@@ -0,0 +1,69 @@
+package lines
+
+import "strings"
+
+//RepairDoubleUnwrap removes blank padding lines a previous unwrap left in text, e.g. when
+//an unwrapped file was saved over its source. Returns the repaired text and whether anything changed.
+//Padding can't be told from blank lines put there on purpose, so the heuristic is conservative:
+//text with a line still ending in "\\" isn't touched at all, and only a run of two or more
+//empty lines (no white space) after a line too long to be an ordinary line of text is repaired.
+//A line is too long when it is longer than every line not followed by an empty line, it is
+//then taken to be joined from as many lines as it takes to hold it in that width, and as many
+//empty lines after it as that join left are removed, never more than the run has.
+//Whitespace-only lines and single empty lines are always kept. Note that line numbers change
+func RepairDoubleUnwrap(text string) (string, bool) {
+	lines := strings.Split(text, "\n")
+
+	width := 0
+	for n, line := range lines {
+		if strings.HasSuffix(strings.TrimRight(line, " \r\t"), wrap) {
+			return text, false
+		}
+		padded := n+1 < len(lines)-1 && isEmptyLine(lines[n+1])
+		if !padded && lineWidth(line) > width {
+			width = lineWidth(line)
+		}
+	}
+	if width == 0 {
+		return text, false
+	}
+
+	repaired := make([]string, 0, len(lines))
+	changed := false
+	for n := 0; n < len(lines); n++ {
+		repaired = append(repaired, lines[n])
+
+		if lineWidth(lines[n]) <= width {
+			continue
+		}
+		blanks := 0
+		for n+1+blanks < len(lines)-1 && isEmptyLine(lines[n+1+blanks]) {
+			blanks++
+		}
+		if blanks < 2 {
+			continue
+		}
+		//the fewest lines of at most width the line can be joined from
+		padding := (lineWidth(lines[n])+width-1)/width - 1
+		if padding > blanks {
+			padding = blanks
+		}
+		n += padding
+		changed = true
+	}
+
+	if !changed {
+		return text, false
+	}
+	return strings.Join(repaired, "\n"), true
+}
+
+//lineWidth returns the length of line without white space at its end
+func lineWidth(line string) int {
+	return len(strings.TrimRight(line, " \r\t"))
+}
+
+//isEmptyLine tells if line is what unwrap leaves in place of a joined line
+func isEmptyLine(line string) bool {
+	return line == "" || line == "\r"
+}
@@ -0,0 +1,57 @@
+package lines
+
+import "testing"
+
+func TestRepairDoubleUnwrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		changed bool
+	}{
+		{
+			"double unwrapped",
+			UnwrapString("func main() {\n\tresult := compute(first, second, \\\n\t\tthird, fourth, \\\n\t\tfifth)\n\treturn result\n}\n"),
+			"func main() {\n\tresult := compute(first, second, third, fourth, fifth)\n\treturn result\n}\n",
+			true,
+		},
+		{
+			"intentional blank kept",
+			"short\njoined line xyz\n\n\n\nshort\n",
+			"short\njoined line xyz\n\nshort\n",
+			true,
+		},
+		{
+			"double blank lines between functions",
+			"def one():\n    return 1\n\n\ndef two():\n    return 2\n",
+			"def one():\n    return 1\n\n\ndef two():\n    return 2\n",
+			false,
+		},
+		{
+			"single blank after a long line",
+			"a\nlong line of a paragraph\n\nb\n",
+			"a\nlong line of a paragraph\n\nb\n",
+			false,
+		},
+		{
+			"whitespace padding kept",
+			"a\nlong line of a paragraph\n \n \nb\n",
+			"a\nlong line of a paragraph\n \n \nb\n",
+			false,
+		},
+		{
+			"connector left",
+			"a \\\nlong line of a paragraph\n\n\nb\n",
+			"a \\\nlong line of a paragraph\n\n\nb\n",
+			false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, changed := RepairDoubleUnwrap(test.text)
+			if got != test.want || changed != test.changed {
+				t.Errorf("RepairDoubleUnwrap(%q) = %q, %t, want %q, %t", test.text, got, changed, test.want, test.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package lines
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+//Result is a file unwrapped by UnwrapWithResult with metadata of its source file
+type Result struct {
+	//Path is the unwrapped file, removed by CleanUp. CleanUp returns the error removing it,
+	//calling it again does nothing and returns nil
+	Path    string
+	CleanUp func() error
+
+	//ModTime, Size and Mode are of the source file when it was opened for unwrapping
+	ModTime time.Time
+	Size    int64
+	Mode    os.FileMode
+}
+
+//UnwrapWithResult works as UnwrapWithOptions and also returns the modification time, size
+//and mode of the source file taken from the file opened for unwrapping, e.g. to key a build
+//cache without another os.Stat. CleanUp is never nil
+func UnwrapWithResult(filePath string, opts Options) (Result, error) {
+	var info os.FileInfo
+	newFilePath, cleanUp, err := unwrapFile(context.Background(), filePath, opts, &info)
+	result := Result{Path: newFilePath, CleanUp: cleanUp}
+	if info != nil {
+		result.ModTime, result.Size, result.Mode = info.ModTime(), info.Size(), info.Mode()
+	}
+	return result, err
+}
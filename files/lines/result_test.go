@@ -0,0 +1,47 @@
+package lines
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnwrapWithResult(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	if err := os.Chmod(filePath, 0600); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := UnwrapWithResult(filePath, Options{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.ModTime.Equal(info.ModTime()) || result.Size != info.Size() || result.Mode != info.Mode() {
+		t.Errorf("got %v %d %v, want %v %d %v", result.ModTime, result.Size, result.Mode, info.ModTime(), info.Size(), info.Mode())
+	}
+	if b, err := ioutil.ReadFile(result.Path); err != nil || string(b) != "a b\n\n" {
+		t.Errorf("unwrapped file has %q, %v", b, err)
+	}
+
+	if err := result.CleanUp(); err != nil {
+		t.Error(err)
+	}
+	if err := result.CleanUp(); err != nil {
+		t.Errorf("second CleanUp got %v", err)
+	}
+}
+
+func TestUnwrapWithResultMissing(t *testing.T) {
+	result, err := UnwrapWithResult(filepath.Join(t.TempDir(), "missing"), Options{})
+	if err == nil {
+		t.Fatal("missing file got no error")
+	}
+	if result.CleanUp == nil || result.CleanUp() != nil {
+		t.Error("CleanUp of a failed unwrap isn't a no-op")
+	}
+}
@@ -0,0 +1,189 @@
+package lines
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+//continuationIndent is added to the indentation of a line for lines Rewrap breaks off it
+const continuationIndent = "  "
+
+//Rewrap is the inverse of Unwrap: it breaks lines longer than maxCols runes at white space,
+//ends every broken line with connector and indents lines broken off by two more spaces.
+//White space at a break stays before the connector, so Unwrap(Rewrap(text)) equals
+//Unwrap(text) but for broken lines: white space at the end of a broken line is trimmed as on
+//every joined line, and a broken line ending with a line ending is followed by one more
+//blank line for every break, as Unwrap keeps line numbers. Lines are only broken between tokens, a token longer than maxCols stays whole and
+//makes its line longer. Lines already ending with connector are left as they are.
+//An empty connector or maxCols below 1 returns text as it is
+func Rewrap(text string, maxCols int, connector string) string {
+	if connector == "" || maxCols < 1 {
+		return text
+	}
+
+	var out strings.Builder
+	forEachLine(text, func(line physicalLine) error {
+		return writeRewrapped(&out, line, maxCols, connector)
+	})
+	return out.String()
+}
+
+//writeRewrapped writes line broken as Rewrap breaks it
+func writeRewrapped(w lineWriter, line physicalLine, maxCols int, connector string) error {
+	pieces := []string{line.text}
+	if !strings.HasSuffix(strings.TrimRight(line.text, " \r\n\t"), connector) {
+		pieces = breakLine(line.text, maxCols, connector, runeWidth)
+	}
+	for _, piece := range pieces[:len(pieces)-1] {
+		if _, err := w.WriteString(piece + "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := w.WriteString(pieces[len(pieces)-1] + line.eol)
+	return err
+}
+
+//Wrap is the counterpart of Unwrap: it rewraps filePath as Rewrap does with a backslash,
+//breaking lines longer than maxWidth runes, e.g. to edit unwrapped templates by hand.
+//The file is rewrapped line by line, only the current line is kept in memory. Unwrapping the
+//temp file gives what unwrapping Rewrap of the file's text gives, see Rewrap.
+//Returns: path to a temp file with wrapped content
+//				 function to clean up temp files, calling it again does nothing
+//				 error if something went wrong
+func Wrap(filePath string, maxWidth int) (newFilePath string, cleanUp func(), err error) {
+
+	cleanUp = func() {}
+
+	opts := Options{}
+	if maxWidth < 1 {
+		message := fmt.Sprintf("Maximum width must be positive: %d", maxWidth)
+		opts.logger().Warningf(message)
+		return "", cleanUp, errors.New(message)
+	}
+
+	file, err := opts.openFile(filePath)
+	if err != nil {
+		return "", cleanUp, err
+	}
+	defer file.Close()
+
+	in, err := opts.textReader(filePath, file)
+	if err != nil {
+		return "", cleanUp, err
+	}
+
+	tmpFile, err := opts.tempFile(filePath)
+	if err != nil {
+		return "", cleanUp, err
+	}
+	defer tmpFile.Close()
+
+	cleanUp = ignoreError(opts.removeOnce(tmpFile.Name()))
+
+	out := bufio.NewWriter(writeErrors{tmpFile})
+	err = opts.forEachLineIn(in, func(line physicalLine) error {
+		return writeRewrapped(out, line, maxWidth, wrap)
+	})
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to write wrapped text to: %s: %w", tmpFile.Name(), err)
+		opts.logger().Warningf(err.Error())
+		return tmpFile.Name(), cleanUp, err
+	}
+
+	opts.logger().Infof("Successfuly wrapped lines to temp file %s", tmpFile.Name())
+
+	return tmpFile.Name(), cleanUp, nil
+}
+
+//runeWidth and byteWidth measure text for breakLine in runes or in bytes
+func runeWidth(rune) int {
+	return 1
+}
+
+func byteWidth(r rune) int {
+	return utf8.RuneLen(r)
+}
+
+//width measures text with runeWidth or byteWidth
+func width(text string, runeWidth func(rune) int) int {
+	cols := 0
+	for _, r := range text {
+		cols += runeWidth(r)
+	}
+	return cols
+}
+
+//breakLine breaks text into pieces at most maxCols wide as Rewrap does, every piece but the
+//last one ends with connector. Returns text as the only piece when it needn't or can't be broken
+func breakLine(text string, maxCols int, connector string, runeWidth func(rune) int) []string {
+	var pieces []string
+	indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+	for width(text, runeWidth) > maxCols {
+		cut := breakPoint(text, maxCols-width(connector, runeWidth), runeWidth)
+		if cut == 0 {
+			break
+		}
+		pieces = append(pieces, text[:cut]+connector)
+		text = indent + continuationIndent + text[cut:]
+	}
+	return append(pieces, text)
+}
+
+//breakPoint returns where to break text: the start of the last token after indentation
+//that leaves at most maxCols before it, the start of the first such token when all
+//are further, 0 when text has no token to break before
+func breakPoint(text string, maxCols int, runeWidth func(rune) int) int {
+	first, last := 0, 0
+	cols := 0
+	space, indented := false, false
+	for i, r := range text {
+		isSpace := r == ' ' || r == '\t'
+		if !isSpace && space && indented {
+			if first == 0 {
+				first = i
+			}
+			if cols <= maxCols {
+				last = i
+			}
+		}
+		if !isSpace {
+			indented = true
+		}
+		space = isSpace
+		cols += runeWidth(r)
+	}
+	if last > 0 {
+		return last
+	}
+	return first
+}
+
+//writeBroken writes line broken into lines at most Options.MaxOutLine bytes long, ending
+//broken lines with line endings of lines joined into line while there are any, so line
+//numbers are kept. Returns the lines whose line endings are still to be written
+func (opts Options) writeBroken(w lineWriter, line logicalLine) ([]physicalLine, error) {
+	connector := wrap
+	if len(opts.Connectors) > 0 {
+		connector = opts.Connectors[0].Connector
+	}
+
+	pieces := breakLine(opts.trimCR(line), opts.MaxOutLine, connector, byteWidth)
+	eols := line.lines
+	for _, piece := range pieces[:len(pieces)-1] {
+		eol := opts.lineEnding("\n")
+		if len(eols) > 1 {
+			eol, eols = opts.lineEnding(eols[0].eol), eols[1:]
+		}
+		if _, err := w.WriteString(piece + eol); err != nil {
+			return nil, err
+		}
+	}
+	_, err := w.WriteString(pieces[len(pieces)-1])
+	return eols, err
+}
@@ -0,0 +1,158 @@
+package lines
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxOutLine(t *testing.T) {
+	words := strings.Repeat("word ", 6)
+	tests := []struct {
+		name string
+		text string
+		opts Options
+		want string
+	}{
+		{"short line", "a \\\nb\n", Options{MaxOutLine: 10}, "a b\n\n"},
+		{"broken joined line", "word word \\\nword word\n", Options{MaxOutLine: 12}, "word word \\\n  word word\n"},
+		{"skipped line", "# noexpand " + words + "\n", Options{MaxOutLine: 10, SkipPrefix: "# noexpand"}, "# noexpand " + words + "\n"},
+		{"line before the start marker", words + "\n---\n", Options{MaxOutLine: 10, StartMarker: "---"}, words + "\n---\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxCols   int
+		connector string
+		want      string
+	}{
+		{"short", "aaa bbb\n", 8, "\\", "aaa bbb\n"},
+		{"broken", "aaa bbb ccc\n", 8, "\\", "aaa \\\n  bbb \\\n  ccc\n"},
+		{"indented", "  aaa bbb\n", 8, "\\", "  aaa \\\n    bbb\n"},
+		{"long token", "aaaaaaaaaa bbb\n", 8, "\\", "aaaaaaaaaa \\\n  bbb\n"},
+		{"one token", "aaaaaaaaaa\n", 8, "\\", "aaaaaaaaaa\n"},
+		{"ends with connector", "aaa bbb ccc \\\nd\n", 8, "\\", "aaa bbb ccc \\\nd\n"},
+		{"other connector", "aaa bbb ccc\n", 9, "&&", "aaa &&\n  bbb ccc\n"},
+		{"runes", "äää ööö üüü\n", 8, "\\", "äää \\\n  ööö \\\n  üüü\n"},
+		{"empty connector", "aaa bbb ccc\n", 8, "", "aaa bbb ccc\n"},
+		{"no columns", "aaa bbb ccc\n", 0, "\\", "aaa bbb ccc\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Rewrap(test.text, test.maxCols, test.connector); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRewrapRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string //Unwrap(Rewrap(text))
+	}{
+		{"not broken", "aaa\nbbb \\\nccc\n", "aaa\nbbb ccc\n\n"},
+		{"broken", "aaa bbb ccc\nx\n", "aaa bbb ccc\n\n\nx\n"},
+		{"broken without line ending", "x\naaa bbb ccc", "x\naaa bbb ccc"},
+		{"broken continuation", "aaa \\\nbbb ccc ddd\n", "aaa bbb ccc ddd\n\n\n\n"},
+		{"spaces at a break", "aaa  bbb\tccc\n", "aaa  bbb\tccc\n\n\n"},
+		{"trailing spaces", "aaa bbb  \n", "aaa bbb\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := UnwrapString(Rewrap(test.text, 8, wrap))
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			//only blank lines and trimmed white space tell it from unwrapping text itself
+			if trimBlank(got) != trimBlank(UnwrapString(test.text)) {
+				t.Errorf("lines of %q differ from %q", got, UnwrapString(test.text))
+			}
+		})
+	}
+}
+
+//trimBlank drops blank lines and white space at the end of lines of text
+func trimBlank(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimRight(line, " \t"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth int
+		want     string
+	}{
+		{"short", "aaa bbb\n", 8, "aaa bbb\n"},
+		{"broken", "aaa bbb ccc\nx\n", 8, "aaa \\\n  bbb \\\n  ccc\nx\n"},
+		{"broken without line ending", "x\naaa bbb ccc", 8, "x\naaa \\\n  bbb \\\n  ccc"},
+		{"CRLF", "aaa bbb ccc\r\nx\r\n", 8, "aaa \\\n  bbb \\\n  ccc\r\nx\r\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := writeTemp(t, "page.tmpl", test.text)
+			newFilePath, cleanUp, err := Wrap(filePath, test.maxWidth)
+			defer cleanUp()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadFile(newFilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != test.want || string(b) != Rewrap(test.text, test.maxWidth, wrap) {
+				t.Errorf("got %q, want %q", b, test.want)
+			}
+			//the round trip Rewrap documents
+			if UnwrapString(string(b)) != UnwrapString(Rewrap(test.text, test.maxWidth, wrap)) {
+				t.Errorf("unwrapped %q, want %q", UnwrapString(string(b)), UnwrapString(Rewrap(test.text, test.maxWidth, wrap)))
+			}
+
+			cleanUp()
+			if _, err := os.Stat(newFilePath); !os.IsNotExist(err) {
+				t.Errorf("temp file left after cleanUp: %v", err)
+			}
+		})
+	}
+}
+
+func TestWrapErrors(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "aaa bbb ccc\n")
+	if _, cleanUp, err := Wrap(filePath, 0); err == nil {
+		cleanUp()
+		t.Error("got no error for width 0")
+	}
+	if _, cleanUp, err := Wrap(filepath.Join(t.TempDir(), "missing.tmpl"), 8); !errors.Is(err, ErrOpen) {
+		cleanUp()
+		t.Errorf("got %v, want an open error", err)
+	}
+	if _, cleanUp, err := Wrap(writeTemp(t, "bin", "a\x00b\n"), 8); !errors.Is(err, ErrBinaryInput) {
+		cleanUp()
+		t.Errorf("got %v, want ErrBinaryInput", err)
+	}
+}
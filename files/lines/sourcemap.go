@@ -0,0 +1,70 @@
+package lines
+
+import (
+	"sort"
+	"strings"
+)
+
+//SourceMap translates positions in unwrapped text back to the text it was unwrapped from,
+//e.g. a template parse error in an unwrapped temp file to the line and column of the source
+type SourceMap struct {
+	//joined has segments of every joined line by its output line, other lines map to themselves
+	joined map[int][]mappedSegment
+}
+
+//mappedSegment is where a segment of a joined line starts in the output line and in its source line
+type mappedSegment struct {
+	col     int //byte offset in the output line
+	srcLine int
+	srcCol  int //byte offset in the source line
+}
+
+//UnwrapWithSourceMap works as UnwrapString and also returns a map from positions in the
+//unwrapped text to positions in text
+func UnwrapWithSourceMap(text string) (string, *SourceMap) {
+	opts := Options{}
+	sourceMap := &SourceMap{joined: map[int][]mappedSegment{}}
+	var out strings.Builder
+	var u *unwrapper
+	u = opts.newUnwrapper(wrap, func(line logicalLine) error {
+		if len(line.lines) > 1 {
+			sourceMap.joined[line.first] = u.mapSegments(line)
+		}
+		return opts.writeLine(&out, line)
+	})
+	forEachLine(text, u.add)
+	u.close()
+	return out.String(), sourceMap
+}
+
+//mapSegments returns where segments of joined line start, as flush joins them
+func (u *unwrapper) mapSegments(line logicalLine) []mappedSegment {
+	segments := make([]mappedSegment, len(line.lines))
+	col := 0
+	for n, physical := range line.lines {
+		segments[n] = mappedSegment{col: col, srcLine: line.first + n}
+		if n > 0 {
+			//joined lines lose their indentation
+			segments[n].srcCol = len(physical.text) - len(u.trimLeft(physical.text))
+		}
+		col += len(u.segment(line.lines, n))
+	}
+	return segments
+}
+
+//ToOriginal returns the source line and column of line and col in the unwrapped text,
+//all starting with 1 and columns counted in bytes. A position on a joined line maps to the
+//source line its segment comes from, any other position maps to itself as unwrapping keeps
+//line numbers
+func (m *SourceMap) ToOriginal(line int, col int) (srcLine int, srcCol int) {
+	segments, ok := m.joined[line]
+	if !ok || col < 1 {
+		return line, col
+	}
+	n := sort.Search(len(segments), func(i int) bool { return segments[i].col > col-1 }) - 1
+	if n < 0 {
+		n = 0
+	}
+	segment := segments[n]
+	return segment.srcLine, segment.srcCol + col - segment.col
+}
@@ -0,0 +1,53 @@
+package lines
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	text := "ab \\\n  cd \\\nef\nx\n"
+	unwrapped, sourceMap := UnwrapWithSourceMap(text)
+	if unwrapped != UnwrapString(text) {
+		t.Fatalf("got %q, want %q", unwrapped, UnwrapString(text))
+	}
+
+	tests := []struct {
+		name            string
+		line, col       int
+		srcLine, srcCol int
+	}{
+		{"start of a joined line", 1, 1, 1, 1},
+		{"space before a connector", 1, 3, 1, 3},
+		{"start of an indented segment", 1, 4, 2, 3},
+		{"inside a segment", 1, 5, 2, 4},
+		{"last segment", 1, 7, 3, 1},
+		{"end of the last segment", 1, 8, 3, 2},
+		{"blank line of a joined line", 2, 1, 2, 1},
+		{"line not joined", 4, 1, 4, 1},
+		{"column before the line", 1, 0, 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srcLine, srcCol := sourceMap.ToOriginal(test.line, test.col)
+			if srcLine != test.srcLine || srcCol != test.srcCol {
+				t.Errorf("%d:%d maps to %d:%d, want %d:%d", test.line, test.col, srcLine, srcCol, test.srcLine, test.srcCol)
+			}
+		})
+	}
+}
+
+func TestSourceMapBytes(t *testing.T) {
+	//every byte of an unwrapped line maps to the same byte in the source
+	text := "x := f(a, \\\n\tb,\t\\\n    c)\ny \\\nz\n"
+	unwrapped, sourceMap := UnwrapWithSourceMap(text)
+	srcLines := strings.Split(text, "\n")
+	for i, line := range strings.Split(unwrapped, "\n") {
+		for col := 1; col <= len(line); col++ {
+			srcLine, srcCol := sourceMap.ToOriginal(i+1, col)
+			if src := srcLines[srcLine-1]; srcCol > len(src) || src[srcCol-1] != line[col-1] {
+				t.Errorf("%d:%d %q maps to %d:%d of %q", i+1, col, line[col-1], srcLine, srcCol, src)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package lines
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+//spill moves text of the current wrap run to a temp file once the run is longer than
+//Options.SpillThreshold bytes, later lines of the run are written there as they come.
+//When the temp file can't be created the run is kept in memory
+func (u *unwrapper) spill() error {
+	if u.opts.SpillThreshold <= 0 || u.spillFailed {
+		return nil
+	}
+
+	last := len(u.run.lines) - 1
+	if u.spillFile == nil {
+		u.runSize += int64(len(u.run.lines[last].text))
+		if u.runSize <= u.opts.SpillThreshold {
+			return nil
+		}
+
+		spillFile, err := ioutil.TempFile(u.opts.tempDir(u.opts.filePath), "unwrap-spill-*")
+		if err != nil {
+			u.opts.logger().Warningf("Failed to create a spill file, keeping line %d in memory: %v", u.run.first, err)
+			u.spillFailed = true
+			return nil
+		}
+		u.spillFile, u.spillOut = spillFile, bufio.NewWriter(spillFile)
+		u.opts.logger().Infof("Spilling line %d to %s", u.run.first, spillFile.Name())
+		for n := range u.run.lines[:last] {
+			if err := u.spillLine(n); err != nil {
+				return err
+			}
+		}
+	}
+	return u.spillLine(last)
+}
+
+//spillLine writes the segment of the n-th line of the run to the spill file
+func (u *unwrapper) spillLine(n int) error {
+	line := &u.run.lines[n]
+	if _, err := u.spillOut.WriteString(u.segment(u.run.lines, n)); err != nil {
+		return u.spillError()
+	}
+	line.text = ""
+	return nil
+}
+
+//sendSpilled sends a run whose text is in the spill file
+func (u *unwrapper) sendSpilled(run logicalLine) error {
+	if err := u.spillOut.Flush(); err != nil {
+		return u.spillError()
+	}
+	run.spill = u.spillFile
+	u.spillFile, u.spillOut, u.runSize = nil, nil, 0
+
+	if err := u.trace(TraceEvent{Event: "join", Line: run.first, Lines: len(run.lines)}); err != nil {
+		removeSpilled(run.spill)
+		return err
+	}
+	return u.send(run)
+}
+
+//abort closes and removes the spill file of an unfinished run,
+//called when unwrapping fails before the run is sent
+func (u *unwrapper) abort() {
+	if u.spillFile == nil {
+		return
+	}
+	removeSpilled(u.spillFile)
+	u.spillFile, u.spillOut = nil, nil
+}
+
+func (u *unwrapper) spillError() error {
+	message := "Failed to write to a spill file"
+	u.opts.logger().Warningf(message)
+	u.abort()
+	return errors.New(message)
+}
+
+//removeSpilled closes and removes spillFile
+func removeSpilled(spillFile *os.File) {
+	spillFile.Close()
+	os.Remove(spillFile.Name())
+}
+
+//copySpilled copies text of a spilled line to w and removes the spill file
+func copySpilled(w io.Writer, spillFile *os.File) error {
+	defer removeSpilled(spillFile)
+
+	if _, err := spillFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, spillFile)
+	return err
+}
@@ -0,0 +1,96 @@
+package lines
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSpillThreshold(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"short runs", strings.Repeat("short line\t \n", 10), strings.Repeat("short line\n", 10)},
+		{"long run", "aaaaaaaaaa \\\nbbbbbbbbbb \\\ncccccccccc\nshort line\t \n", "aaaaaaaaaa bbbbbbbbbb cccccccccc\n\n\nshort line\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			opts := Options{SpillThreshold: 20, TrimAllTrailingWhitespace: true, TempDir: t.TempDir()}
+			if err := UnwrapStreamWithOptions(strings.NewReader(test.text), &out, opts); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.want {
+				t.Errorf("unwrapped %q to %q, want %q", test.text, out.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestSpillFileRemovedOnError(t *testing.T) {
+	long := strings.Repeat("a", 40) + " \\\n"
+	tests := []struct {
+		name string
+		text string
+		opts Options
+	}{
+		{"dangling connector", long + long, Options{FailOnDanglingConnector: true}},
+		{"too many continuations", long + long + long + "b\n", Options{MaxContinuations: 2}},
+		{"line too long", long + long + strings.Repeat("c", 100) + "\n", Options{MaxPhysicalLineLength: 80}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			test.opts.SpillThreshold, test.opts.TempDir = 20, tmpDir
+
+			var out strings.Builder
+			if err := UnwrapStreamWithOptions(strings.NewReader(test.text), &out, test.opts); err == nil {
+				t.Fatal("stream got no error")
+			}
+			if _, err := UnwrapStringWithOptions(test.text, test.opts); err == nil {
+				t.Fatal("string got no error")
+			}
+			if infos, err := ioutil.ReadDir(tmpDir); err != nil || len(infos) > 0 {
+				t.Errorf("failed unwraps left %d spill files, %v", len(infos), err)
+			}
+		})
+	}
+}
+
+func TestSpillFileLocation(t *testing.T) {
+	long := strings.Repeat("a", 40) + " \\\n"
+	srcPath := writeTemp(t, "page.tmpl", long+long+"b\n")
+	tmpDir := t.TempDir()
+	tests := []struct {
+		name string
+		opts Options
+		file bool //a file is unwrapped, not a string
+		dir  string
+	}{
+		{"TempDir", Options{TempDir: tmpDir}, true, tmpDir},
+		{"next to source", Options{TempNextToSource: true}, true, filepath.Dir(srcPath)},
+		{"string next to source", Options{TempNextToSource: true}, false, os.TempDir()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			test.opts.SpillThreshold, test.opts.Logger = 20, logger
+			if test.file {
+				_, cleanUp, err := UnwrapWithOptions(srcPath, test.opts)
+				defer cleanUp()
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if _, err := UnwrapStringWithOptions(long+long+"b\n", test.opts); err != nil {
+				t.Fatal(err)
+			}
+			if !logger.logged("I Spilling line 1 to " + filepath.Join(test.dir, "unwrap-spill-")) {
+				t.Errorf("got %q, want a spill file in %s", logger.messages, test.dir)
+			}
+		})
+	}
+}
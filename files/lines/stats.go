@@ -0,0 +1,151 @@
+package lines
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	log "github.com/google/logger"
+)
+
+//Stats describes how much wrapping a text uses
+type Stats struct {
+	//Files is the number of files processed
+	Files int
+	//PhysicalLines is the number of lines in the source
+	PhysicalLines int
+	//LogicalLines is the number of lines left when wrap runs are joined
+	LogicalLines int
+	//Joins is the number of wrap runs joined into one line, the joined groups
+	Joins int
+	//LinesMerged is the number of lines joined into the line before them,
+	//the number of blank lines left in their place
+	LinesMerged int
+	//MaxChainLen is the number of physical lines in the longest joined wrap run, 0 without joins
+	MaxChainLen int
+	//PerFile holds stats of every processed file by path, DirStats only
+	PerFile map[string]Stats
+}
+
+func (stats *Stats) add(other Stats) {
+	stats.Files += other.Files
+	stats.PhysicalLines += other.PhysicalLines
+	stats.LogicalLines += other.LogicalLines
+	stats.Joins += other.Joins
+	stats.LinesMerged += other.LinesMerged
+	if other.MaxChainLen > stats.MaxChainLen {
+		stats.MaxChainLen = other.MaxChainLen
+	}
+}
+
+//count adds a logical line to stats
+func (stats *Stats) count(line logicalLine) {
+	stats.PhysicalLines += len(line.lines)
+	stats.LogicalLines++
+	if len(line.lines) > 1 {
+		stats.Joins++
+		stats.LinesMerged += len(line.lines) - 1
+		if len(line.lines) > stats.MaxChainLen {
+			stats.MaxChainLen = len(line.lines)
+		}
+	}
+}
+
+//UnwrapWithStats works as UnwrapString and also returns Stats of text, Files is 0
+func UnwrapWithStats(text string) (out string, stats Stats) {
+	opts := Options{}
+	var builder strings.Builder
+	u := opts.newUnwrapper(wrap, func(line logicalLine) error {
+		stats.count(line)
+		return opts.writeLine(&builder, line)
+	})
+	forEachLine(text, u.add)
+	u.close()
+	return builder.String(), stats
+}
+
+//DirStats walks root and sums up Stats of all files with extension ext ("" for any file).
+//Symlinks are not followed. PerFile of the result holds stats of every file.
+//A binary file fails with ErrBinaryInput
+func DirStats(root, ext, connector string) (Stats, error) {
+	total := Stats{PerFile: map[string]Stats{}}
+
+	if connector == "" {
+		message := "Connector must not be empty"
+		log.Warningf(message)
+		return total, errors.New(message)
+	}
+
+	filePaths, _, err := Options{}.walkFiles(context.Background(), root, func(path string) bool {
+		return ext == "" || filepath.Ext(path) == ext
+	}, false)
+	if err != nil {
+		return total, err
+	}
+
+	for _, filePath := range filePaths {
+		stats, err := fileStats(filePath, connector)
+		if err != nil {
+			return total, err
+		}
+		total.PerFile[filePath] = stats
+		total.add(stats)
+	}
+	return total, nil
+}
+
+func fileStats(filePath string, connector string) (Stats, error) {
+	file, err := Options{}.openFile(filePath)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer file.Close()
+
+	in, err := Options{}.textReader(filePath, file)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats, err := readStats(in, connector)
+	if err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		log.Warningf(err.Error())
+		return Stats{}, err
+	}
+	stats.Files = 1
+	return stats, nil
+}
+
+func readStats(r io.Reader, connector string) (stats Stats, err error) {
+	u := Options{}.newUnwrapper(connector, func(line logicalLine) error {
+		stats.count(line)
+		return nil
+	})
+	if err = u.opts.forEachLineIn(r, u.add); err != nil {
+		return Stats{}, err
+	}
+	return stats, u.close()
+}
+
+//RunLengthHistogram maps length of wrap runs in text (number of physical lines joined
+//into one) to how many runs of that length there are. Lines that aren't wrapped don't count
+func RunLengthHistogram(text string, connector string) map[int]int {
+	histogram := map[int]int{}
+	if connector == "" {
+		return histogram
+	}
+
+	u := Options{}.newUnwrapper(connector, func(line logicalLine) error {
+		if len(line.lines) > 1 {
+			histogram[len(line.lines)]++
+		}
+		return nil
+	})
+	forEachLine(text, u.add)
+	u.close()
+
+	return histogram
+}
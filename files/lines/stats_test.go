@@ -0,0 +1,95 @@
+package lines
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnwrapWithStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		stats Stats
+	}{
+		{"empty", "", Stats{}},
+		{"not joined", "a\nb\n", Stats{PhysicalLines: 2, LogicalLines: 2}},
+		{"joined", "a \\\nb\nc\n", Stats{PhysicalLines: 3, LogicalLines: 2, Joins: 1, LinesMerged: 1, MaxChainLen: 2}},
+		{"two runs", "a \\\nb \\\nc\nd \\\ne\n", Stats{PhysicalLines: 5, LogicalLines: 2, Joins: 2, LinesMerged: 3, MaxChainLen: 3}},
+		{"dangling", "a \\\n", Stats{PhysicalLines: 1, LogicalLines: 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, stats := UnwrapWithStats(test.text)
+			if out != UnwrapString(test.text) {
+				t.Errorf("got %q, want %q", out, UnwrapString(test.text))
+			}
+			if !reflect.DeepEqual(stats, test.stats) {
+				t.Errorf("got %+v, want %+v", stats, test.stats)
+			}
+		})
+	}
+}
+
+func TestDirStats(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.tmpl":       "a \\\nb \\\nc\nd\n",
+		"sub/b.tmpl":   "e \\\nf\n",
+		"sub/c.tmpl":   "g\n",
+		"sub/skip.txt": "h \\\ni\n",
+	}
+	for name, text := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filePath, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := DirStats(root, ".tmpl", "\\")
+	if err != nil {
+		t.Fatal(err)
+	}
+	perFile := stats.PerFile
+	stats.PerFile = nil
+	want := Stats{Files: 3, PhysicalLines: 7, LogicalLines: 4, Joins: 2, LinesMerged: 3, MaxChainLen: 3}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("DirStats = %+v, want %+v", stats, want)
+	}
+
+	wantPerFile := map[string]Stats{
+		filepath.Join(root, "a.tmpl"):        {Files: 1, PhysicalLines: 4, LogicalLines: 2, Joins: 1, LinesMerged: 2, MaxChainLen: 3},
+		filepath.Join(root, "sub", "b.tmpl"): {Files: 1, PhysicalLines: 2, LogicalLines: 1, Joins: 1, LinesMerged: 1, MaxChainLen: 2},
+		filepath.Join(root, "sub", "c.tmpl"): {Files: 1, PhysicalLines: 1, LogicalLines: 1},
+	}
+	if !reflect.DeepEqual(perFile, wantPerFile) {
+		t.Errorf("PerFile = %+v, want %+v", perFile, wantPerFile)
+	}
+}
+
+func TestRunLengthHistogram(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		connector string
+		want      map[int]int
+	}{
+		{"known runs", "a \\\nb\nc\nd \\\ne \\\nf\ng \\\nh\n", "\\", map[int]int{2: 2, 3: 1}},
+		{"no runs", "a\nb\n", "\\", map[int]int{}},
+		{"dangling connector", "a \\\nb \\", "\\", map[int]int{2: 1}},
+		{"other connector", "a &&\nb \\\nc\n", "&&", map[int]int{2: 1}},
+		{"no connector", "a \\\nb\n", "", map[int]int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RunLengthHistogram(test.text, test.connector); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("RunLengthHistogram(%q) = %v, want %v", test.text, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package lines
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	log "github.com/google/logger"
+)
+
+//ErrLineTooLong is returned in a LineError when a physical line is longer than Options.MaxPhysicalLineLength
+var ErrLineTooLong = errors.New("line too long")
+
+//UnwrapStream unwraps lines read from r and writes them to w as Unwrap does, including
+//blank lines in place of joined lines, e.g. a template read from stdin or an HTTP response
+//without a file on disk. Only the current wrap run is kept in memory.
+//Lines of any length are supported, a single line is held in memory as a whole
+func UnwrapStream(r io.Reader, w io.Writer) error {
+	return Options{}.unwrapTo(r, w, wrap)
+}
+
+//UnwrapStreamContext works as UnwrapStream and stops with ctx.Err() once ctx is done,
+//checked before every read from r
+func UnwrapStreamContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	return Options{}.unwrapTo(contextReader{ctx: ctx, r: r}, w, wrap)
+}
+
+//UnwrapStreamWithOptions works as UnwrapStream, opts changes how lines are unwrapped
+func UnwrapStreamWithOptions(r io.Reader, w io.Writer, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	return opts.unwrapTo(r, w, wrap)
+}
+
+//UnwrapStreamWithLines unwraps lines read from r and calls emit for every logical line
+//with the number of the source line it starts on, starting with 1.
+//Blank lines that Unwrap leaves in place of joined lines are not emitted.
+//Only the current wrap run is kept in memory. Lines of any length are supported.
+//An error returned by emit stops reading and is returned as is
+func UnwrapStreamWithLines(r io.Reader, connector string, emit func(lineNo int, line string) error) error {
+	if connector == "" {
+		message := "Connector must not be empty"
+		log.Warningf(message)
+		return errors.New(message)
+	}
+
+	u := Options{}.newUnwrapper(connector, func(line logicalLine) error {
+		return emit(line.first, line.text)
+	})
+	if err := u.opts.forEachLineIn(r, u.add); err != nil {
+		return err
+	}
+	return u.close()
+}
+
+//unwrapTo unwraps lines read from r and writes them to w as unwrapLines does,
+//through Options.OutputEncoder when it is set
+func (opts Options) unwrapTo(r io.Reader, w io.Writer, connector string) error {
+	w = writeErrors{w}
+	var encoder io.WriteCloser
+	if opts.OutputEncoder != nil {
+		encoder = opts.OutputEncoder(w)
+		w = encoder
+	}
+
+	if opts.DominantLineEnding {
+		in := bufio.NewReaderSize(r, sniffLen)
+		head, _ := in.Peek(sniffLen) //a read error is returned by the next read of in
+		opts, r = opts.dominantLineEnding(string(head)), in
+	}
+
+	out := bufio.NewWriter(w)
+	u := opts.newUnwrapper(connector, func(line logicalLine) error {
+		return opts.writeLine(out, line)
+	})
+	if err := opts.forEachLineIn(r, u.add); err != nil {
+		u.abort()
+		return err
+	}
+	if err := u.close(); err != nil {
+		u.abort()
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if encoder != nil {
+		return encoder.Close()
+	}
+	return nil
+}
+
+//forEachLineIn calls fn for every physical line read from r. A line growing over
+//Options.MaxPhysicalLineLength fails with ErrLineTooLong as soon as it is read
+//that far, so it is never held in memory as a whole.
+//Lines are collected from buffer-sized slices, so unlike with bufio.Scanner there is no
+//token size limit and a line longer than the buffer is never cut or rejected
+func (opts Options) forEachLineIn(r io.Reader, fn func(line physicalLine) error) error {
+	maxLength := opts.MaxPhysicalLineLength
+	in := bufio.NewReader(r)
+	var text []byte
+	lineNo := 1
+	for {
+		chunk, err := in.ReadSlice('\n')
+		text = append(text, chunk...)
+		if err == bufio.ErrBufferFull {
+			//one more byte may be the \r of a \r\n line ending
+			if maxLength > 0 && len(text) > maxLength+1 {
+				return opts.lineTooLong(lineNo)
+			}
+			continue
+		}
+		if len(text) > 0 {
+			if fnErr := fn(newPhysicalLine(string(text))); fnErr != nil {
+				return fnErr
+			}
+			text = text[:0]
+			lineNo++
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			err = fmt.Errorf("Failed to read lines: %w", err)
+			opts.logger().Warningf(err.Error())
+			return err
+		}
+	}
+}
+
+//lineTooLong returns a LineError with ErrLineTooLong for physical line lineNo
+func (opts Options) lineTooLong(lineNo int) error {
+	return opts.lineError(lineNo, fmt.Errorf("%w: at most %d bytes allowed", ErrLineTooLong, opts.MaxPhysicalLineLength))
+}
@@ -0,0 +1,151 @@
+package lines
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnwrapStreamWithLines(t *testing.T) {
+	type line struct {
+		lineNo int
+		text   string
+	}
+	text := "a\nb \\\nc \\\nd\ne\n\nf \\\ng"
+	want := []line{{1, "a"}, {2, "b c d"}, {5, "e"}, {6, ""}, {7, "f g"}}
+
+	var got []line
+	err := UnwrapStreamWithLines(strings.NewReader(text), "\\", func(lineNo int, text string) error {
+		got = append(got, line{lineNo, text})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("emitted %v, want %v", got, want)
+	}
+}
+
+func TestUnwrapStreamWithLinesAbort(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := UnwrapStreamWithLines(strings.NewReader("a\nb\nc\n"), "\\", func(lineNo int, text string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("got %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+//endlessLine reads as a line without a line ending that never ends
+type endlessLine struct{}
+
+func (endlessLine) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
+
+func TestMaxPhysicalLineLength(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		line int //of the error, 0 for none
+	}{
+		{"at the limit", "abcd \\\nefgh\n", 0},
+		{"too long", "abcd\nefghijk\n", 2},
+		{"too long last line", "abcd\nefghijk", 2},
+		{"line ending not counted", "abcd\r\nefgh\r\n", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := UnwrapStreamWithOptions(strings.NewReader(test.text), &out, Options{MaxPhysicalLineLength: 6})
+			var lineErr *LineError
+			switch {
+			case test.line == 0 && err != nil:
+				t.Errorf("got %v", err)
+			case test.line > 0 && (!errors.As(err, &lineErr) || !errors.Is(err, ErrLineTooLong) || lineErr.Line != test.line):
+				t.Errorf("got %v, want %v at line %d", err, ErrLineTooLong, test.line)
+			}
+		})
+	}
+
+	//reading stops at the limit, an endless line isn't read into memory
+	var out bytes.Buffer
+	if err := UnwrapStreamWithOptions(endlessLine{}, &out, Options{MaxPhysicalLineLength: 1 << 16}); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("endless line got %v", err)
+	}
+}
+
+func TestOutputEncoder(t *testing.T) {
+	text := "a \\\nb\nc\n"
+	want := base64.StdEncoding.EncodeToString([]byte(UnwrapString(text)))
+	opts := Options{OutputEncoder: func(w io.Writer) io.WriteCloser {
+		return base64.NewEncoder(base64.StdEncoding, w)
+	}}
+
+	var out bytes.Buffer
+	if err := UnwrapStreamWithOptions(strings.NewReader(text), &out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("stream encoded to %q, want %q", out.String(), want)
+	}
+
+	opts.TempDir = t.TempDir()
+	newFilePath, cleanUp, err := UnwrapWithOptions(writeTemp(t, "a.txt", text), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+	b, err := ioutil.ReadFile(newFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != want {
+		t.Errorf("file encoded to %q, want %q", b, want)
+	}
+}
+
+func TestUnwrapStreamLongLine(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"one line", long + "\n", long + "\n"},
+		{"joined", long + "\\\n" + long + "\n", long + long + "\n\n"},
+		{"no line ending", long, long},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := UnwrapStream(strings.NewReader(test.text), &out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.want {
+				t.Errorf("got %d bytes, want %d bytes intact", out.Len(), len(test.want))
+			}
+		})
+	}
+}
+
+func TestUnwrapStreamContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	if err := UnwrapStreamContext(ctx, strings.NewReader("a \\\nb\n"), &out); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled stream got %v", err)
+	}
+}
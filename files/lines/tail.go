@@ -0,0 +1,122 @@
+package lines
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	log "github.com/google/logger"
+)
+
+//tailPollInterval is how often UnwrapTail checks the file for new content
+const tailPollInterval = 250 * time.Millisecond
+
+//UnwrapTail follows filePath as it grows (like tail -f) and calls emit for every logical line
+//as soon as it is complete: a physical line is only processed once its line ending is written
+//and a wrap run is held until the line that ends it arrives.
+//When the file shrinks it was truncated and is read again from the start,
+//when filePath is replaced by another file (rotation) the new file is read from the start.
+//Returns ctx.Err() when ctx is done, ErrBinaryInput when the file isn't text or the error returned by emit
+func UnwrapTail(ctx context.Context, filePath string, emit func(line string) error) error {
+	in, info, err := openTail(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		in.Close()
+	}()
+
+	newUnwrapper := func() *unwrapper {
+		return Options{}.newUnwrapper(wrap, func(line logicalLine) error {
+			return emit(line.text)
+		})
+	}
+	u := newUnwrapper()
+
+	var offset int64
+	var pending []byte
+	var sniffed int //bytes checked to be text, see checkText
+	buf := make([]byte, 32*1024)
+
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := in.Read(buf)
+		if n > 0 && sniffed < sniffLen {
+			//the file may still be growing, so it is checked as it is read
+			if err := (Options{}).checkText(filePath, buf[:n]); err != nil {
+				return err
+			}
+			sniffed += n
+		}
+		if n > 0 {
+			offset += int64(n)
+			pending = append(pending, buf[:n]...)
+			for {
+				i := bytes.IndexByte(pending, '\n')
+				if i < 0 {
+					break
+				}
+				if err := u.add(newPhysicalLine(string(pending[:i+1]))); err != nil {
+					return err
+				}
+				pending = pending[i+1:]
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+			log.Warningf(err.Error())
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		current, err := os.Stat(filePath)
+		switch {
+		case err != nil:
+			//file is being rotated, wait for the new one
+		case !os.SameFile(info, current):
+			if err := u.close(); err != nil {
+				return err
+			}
+			log.Infof("File %s was replaced, reading the new one", filePath)
+			in.Close()
+			if in, info, err = openTail(filePath); err != nil {
+				return err
+			}
+			u, offset, pending, sniffed = newUnwrapper(), 0, nil, 0
+		case current.Size() < offset:
+			log.Infof("File %s was truncated, reading it from the start", filePath)
+			if _, err := in.Seek(0, io.SeekStart); err != nil {
+				err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+				log.Warningf(err.Error())
+				return err
+			}
+			u, offset, pending, sniffed = newUnwrapper(), 0, nil, 0
+		}
+	}
+}
+
+func openTail(filePath string) (*os.File, os.FileInfo, error) {
+	in, err := Options{}.openFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := in.Stat()
+	if err != nil {
+		in.Close()
+		err = fmt.Errorf("Failed to open file: %s: %w", filePath, err)
+		log.Warningf(err.Error())
+		return nil, nil, err
+	}
+	return in, info, nil
+}
@@ -0,0 +1,69 @@
+package lines
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUnwrapTail(t *testing.T) {
+	filePath := writeTemp(t, "live.tmpl", "a\nb \\\n")
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	lines := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- UnwrapTail(ctx, filePath, func(line string) error {
+			lines <- line
+			return nil
+		})
+	}()
+
+	expect := func(want string) {
+		t.Helper()
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("emitted %q, want %q", got, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("%q not emitted", want)
+		}
+	}
+
+	expect("a")
+	//the wrap run is held until the line ending it is written
+	if _, err := file.WriteString("c \\\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("d\ne"); err != nil {
+		t.Fatal(err)
+	}
+	expect("b c d")
+	if _, err := file.WriteString("\n"); err != nil {
+		t.Fatal(err)
+	}
+	expect("e")
+
+	//a truncated file is read from the start
+	if err := file.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString("f\n"); err != nil {
+		t.Fatal(err)
+	}
+	expect("f")
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("UnwrapTail returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,62 @@
+package lines
+
+import (
+	"errors"
+	"path/filepath"
+	"text/template"
+
+	log "github.com/google/logger"
+)
+
+//UnwrapTemplates unwraps filePath in memory and parses it as a set of templates,
+//every {{define}} block becomes a template associated with the returned one.
+//The returned template is named after the file, as template.ParseFiles does, and
+//unwrapping keeps line numbers, so parse errors point to the source file and line
+func UnwrapTemplates(filePath string, funcs template.FuncMap) (*template.Template, error) {
+	text, err := Options{}.readFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err = Options{}.unwrapText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return template.New(filepath.Base(filePath)).Funcs(funcs).Parse(text)
+}
+
+//ParseUnwrapped works as t.ParseFiles with every file unwrapped in memory first: templates are
+//named after base names of filePaths, so parse errors point to the source file and line
+//instead of a temp file. A nil t is created from the first file as template.ParseFiles does
+func ParseUnwrapped(t *template.Template, filePaths ...string) (*template.Template, error) {
+	if len(filePaths) == 0 {
+		message := "No files to parse"
+		log.Warningf(message)
+		return nil, errors.New(message)
+	}
+
+	for _, filePath := range filePaths {
+		text, err := Options{}.readFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		text, err = Options{}.unwrapText(text)
+		if err != nil {
+			return nil, err
+		}
+
+		name := filepath.Base(filePath)
+		if t == nil {
+			t = template.New(name)
+		}
+		tmpl := t
+		if name != t.Name() {
+			tmpl = t.New(name)
+		}
+		if _, err := tmpl.Parse(text); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
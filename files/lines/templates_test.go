@@ -0,0 +1,99 @@
+package lines
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestUnwrapTemplates(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", `{{define "header"}}{{upper \
+	.Title}}{{end}}
+{{define "body"}}{{range .Items}}{{.}}, {{end \
+}}{{end}}
+`)
+	tmpl, err := UnwrapTemplates(filePath, template.FuncMap{"upper": strings.ToUpper})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Name() != "page.tmpl" {
+		t.Errorf("template is named %q", tmpl.Name())
+	}
+
+	for name, want := range map[string]string{"header": "TITLE", "body": "a, b, "} {
+		if tmpl.Lookup(name) == nil {
+			t.Fatalf("no %q template", name)
+		}
+		var out strings.Builder
+		data := map[string]interface{}{"Title": "title", "Items": []string{"a", "b"}}
+		if err := tmpl.ExecuteTemplate(&out, name, data); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("%q executed to %q, want %q", name, out.String(), want)
+		}
+	}
+}
+
+func TestUnwrapTemplatesParseError(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "{{define \"a\"}}{{.A \\\n}}{{end}}\n{{if}}\n")
+	_, err := UnwrapTemplates(filePath, nil)
+	if err == nil || !strings.Contains(err.Error(), "page.tmpl:3") {
+		t.Errorf("got %v, want an error at page.tmpl:3", err)
+	}
+}
+
+func TestParseUnwrapped(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, text string) string {
+		filePath := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filePath, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return filePath
+	}
+	page := write("page.tmpl", "{{template \"header.tmpl\" .}}{{.Body \\\n}}\n")
+	header := write("header.tmpl", "<h1>{{.Title \\\n}}</h1>\n")
+	broken := write("broken.tmpl", "a \\\nb\n{{if}}\n")
+
+	tests := []struct {
+		name      string
+		t         *template.Template
+		filePaths []string
+		want      string //executed template, with an error the text it contains
+		fails     bool
+	}{
+		{"nil template", nil, []string{page, header}, "<h1>title</h1>\n\nbody\n\n", false},
+		{"existing template", template.New("page.tmpl"), []string{page, header}, "<h1>title</h1>\n\nbody\n\n", false},
+		{"parse error", nil, []string{page, broken}, "broken.tmpl:3", true},
+		{"parse error of the first file", nil, []string{broken}, "broken.tmpl:3", true},
+		{"missing file", nil, []string{filepath.Join(dir, "missing.tmpl")}, "missing.tmpl", true},
+		{"no files", nil, nil, "No files", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl, err := ParseUnwrapped(test.t, test.filePaths...)
+			if test.fails {
+				if err == nil || !strings.Contains(err.Error(), test.want) {
+					t.Errorf("got %v, want an error with %q", err, test.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tmpl.Name() != "page.tmpl" {
+				t.Errorf("template is named %q", tmpl.Name())
+			}
+			var out strings.Builder
+			if err := tmpl.Execute(&out, map[string]string{"Title": "title", "Body": "body"}); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != test.want {
+				t.Errorf("executed to %q, want %q", out.String(), test.want)
+			}
+		})
+	}
+}
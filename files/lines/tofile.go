@@ -0,0 +1,90 @@
+package lines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//UnwrapToFile unwraps srcPath to dstPath instead of a temp file, creating parent directories
+//of dstPath as needed. An existing dstPath is an error matching os.ErrExist unless overwrite
+//is set. Lines are written to a temp file in the directory of dstPath and renamed over it,
+//so dstPath is left as it is when unwrapping fails. A dstPath that is srcPath (the same file,
+//also through a link) is unwrapped in place with UnwrapInPlace
+func UnwrapToFile(srcPath string, dstPath string, overwrite bool) error {
+	return UnwrapToFileWithOptions(srcPath, dstPath, overwrite, Options{})
+}
+
+//UnwrapToFileWithOptions works as UnwrapToFile, opts changes how lines are unwrapped
+func UnwrapToFileWithOptions(srcPath string, dstPath string, overwrite bool, opts Options) error {
+	opts = opts.forFile(srcPath)
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	file, err := opts.openFile(srcPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	srcInfo, err := file.Stat()
+	if err != nil {
+		err = fmt.Errorf("Failed to stat file: %s: %w", srcPath, err)
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	dstInfo, err := os.Stat(dstPath)
+	switch {
+	case err == nil && !overwrite:
+		err = fmt.Errorf("Failed to create file: %s: %w", dstPath, writeError(os.ErrExist))
+		opts.logger().Warningf(err.Error())
+		return err
+	case err == nil && os.SameFile(srcInfo, dstInfo):
+		file.Close()
+		return UnwrapInPlaceWithOptions(dstPath, opts)
+	case err == nil:
+	case os.IsNotExist(err):
+		dstInfo = nil
+	default:
+		err = fmt.Errorf("Failed to stat file: %s: %w", dstPath, err)
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	in, err := opts.textReader(srcPath, file)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		err = fmt.Errorf("Failed to create directory for: %s: %w", dstPath, writeError(err))
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+".*")
+	if err != nil {
+		err = fmt.Errorf("Failed to create a temp file next to: %s: %w", dstPath, writeError(err))
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	err = opts.unwrapTo(in, tmpFile, wrap)
+	if err == nil {
+		err = replace(tmpFile, dstPath, dstInfo)
+	} else {
+		tmpFile.Close()
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		err = fmt.Errorf("Failed to unwrap lines to: %s: %w", dstPath, err)
+		opts.logger().Warningf(err.Error())
+		return err
+	}
+
+	opts.logger().Infof("Successfuly unwrapped lines to file %s", dstPath)
+	return nil
+}
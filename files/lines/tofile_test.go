@@ -0,0 +1,106 @@
+package lines
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnwrapToFile(t *testing.T) {
+	text := strings.Repeat("a \\\nb\n", 2000)
+	tests := []struct {
+		name      string
+		src       string
+		dst       string //"" for no existing dst
+		overwrite bool
+		err       error
+		want      string //content of dst afterwards
+	}{
+		{"new file", text, "", false, nil, UnwrapString(text)},
+		{"exists", text, "old\n", false, os.ErrExist, "old\n"},
+		{"overwrite", text, "old\n", true, nil, UnwrapString(text)},
+		{"failed unwrap keeps dst", "a\x00b\n", "old\n", true, ErrBinaryInput, "old\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			dstPath := filepath.Join(dir, "out", "page.tmpl")
+			if test.dst != "" {
+				if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(dstPath, []byte(test.dst), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := UnwrapToFile(writeTemp(t, "page.tmpl", test.src), dstPath, test.overwrite)
+			if test.err == nil && err != nil || test.err != nil && !errors.Is(err, test.err) {
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if b, err := ioutil.ReadFile(dstPath); err != nil || string(b) != test.want {
+				t.Errorf("dst has %d bytes, %v, want %d bytes", len(b), err, len(test.want))
+			}
+			if infos, _ := ioutil.ReadDir(filepath.Dir(dstPath)); len(infos) != 1 {
+				t.Errorf("temp file left next to dst: %d files", len(infos))
+			}
+			if info, err := os.Stat(dstPath); err == nil && test.dst != "" && info.Mode().Perm() != 0600 {
+				t.Errorf("dst mode changed to %v", info.Mode())
+			}
+		})
+	}
+}
+
+func TestUnwrapToFileSameFile(t *testing.T) {
+	//larger than the binary sniff, truncating dst before reading src would lose most of it
+	text := strings.Repeat("a \\\nb\n", 2000) + strings.Repeat("c", 10000) + "\n"
+	tests := []struct {
+		name      string
+		link      bool //dst is a hard link to src
+		overwrite bool
+		want      string
+	}{
+		{"same path", false, true, UnwrapString(text)},
+		{"hard link", true, true, UnwrapString(text)},
+		{"without overwrite", false, false, text},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srcPath := writeTemp(t, "page.tmpl", text)
+			dstPath := srcPath
+			if test.link {
+				dstPath = filepath.Join(filepath.Dir(srcPath), "link.tmpl")
+				if err := os.Link(srcPath, dstPath); err != nil {
+					t.Skipf("can't make a hard link: %v", err)
+				}
+			}
+
+			err := UnwrapToFile(srcPath, dstPath, test.overwrite)
+			if test.overwrite && err != nil || !test.overwrite && !errors.Is(err, os.ErrExist) {
+				t.Fatalf("got %v", err)
+			}
+			if b, err := ioutil.ReadFile(dstPath); err != nil || string(b) != test.want {
+				t.Errorf("dst has %d bytes, %v, want %d bytes", len(b), err, len(test.want))
+			}
+		})
+	}
+}
+
+func TestUnwrapToFileWithOptions(t *testing.T) {
+	srcPath := writeTemp(t, "page.tmpl", "a &&\nb\n")
+	dstPath := filepath.Join(t.TempDir(), "page.tmpl")
+	logger := &recordingLogger{}
+
+	if err := UnwrapToFileWithOptions(srcPath, dstPath, false, Options{Connectors: []ConnectorRule{{Connector: "&&"}}, Logger: logger}); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(dstPath); err != nil || string(b) != "a b\n\n" {
+		t.Errorf("dst is %q, %v, want %q", b, err, "a b\n\n")
+	}
+	if len(logger.messages) == 0 {
+		t.Error("nothing logged to Options.Logger")
+	}
+}
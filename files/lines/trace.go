@@ -0,0 +1,89 @@
+package lines
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/google/logger"
+)
+
+//TraceEvent is a decision of the unwrapper, Options.RecordTo writes them as JSON lines
+type TraceEvent struct {
+	//Event is one of:
+	//"start", the unwrapper started with Text as connector
+	//"read", physical line Line was read, Text and EOL are the line as in the source
+	//"connector", physical line Line ends with the connector, the wrap run goes on
+	//"join", physical lines starting at Line were joined into Text, Lines is how many
+	Event string `json:"event"`
+	Line  int    `json:"line,omitempty"`
+	Lines int    `json:"lines,omitempty"`
+	Text  string `json:"text"`
+	EOL   string `json:"eol,omitempty"`
+}
+
+func (u *unwrapper) trace(event TraceEvent) error {
+	if u.recorder == nil {
+		return nil
+	}
+	if err := u.recorder.Encode(event); err != nil {
+		message := "Failed to record unwrap trace"
+		u.opts.logger().Warningf(message)
+		return errors.New(message)
+	}
+	return nil
+}
+
+//Replay reads a trace recorded by Options.RecordTo, unwraps the lines from its "read" events
+//again and returns an error describing the first event the new run does differently.
+//Only the connector is taken from the trace, the new run uses default Options otherwise
+func Replay(r io.Reader) error {
+	var recorded []TraceEvent
+	var text strings.Builder
+	for decoder := json.NewDecoder(r); ; {
+		var event TraceEvent
+		err := decoder.Decode(&event)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			message := "Failed to read unwrap trace"
+			log.Warningf(message)
+			return errors.New(message)
+		}
+		recorded = append(recorded, event)
+		if event.Event == "read" {
+			text.WriteString(event.Text + event.EOL)
+		}
+	}
+
+	if len(recorded) == 0 || recorded[0].Event != "start" {
+		message := "Unwrap trace doesn't start with a start event"
+		log.Warningf(message)
+		return errors.New(message)
+	}
+
+	var replayed bytes.Buffer
+	if _, err := (Options{RecordTo: &replayed}).unwrapLines(text.String(), recorded[0].Text); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(&replayed)
+	for n, event := range recorded {
+		var again TraceEvent
+		if err := decoder.Decode(&again); err != nil || again != event {
+			message := fmt.Sprintf("Unwrap trace differs at event %d: recorded %+v, replayed %+v", n+1, event, again)
+			log.Warningf(message)
+			return errors.New(message)
+		}
+	}
+	if decoder.More() {
+		message := "Unwrap trace is shorter than the replayed one"
+		log.Warningf(message)
+		return errors.New(message)
+	}
+	return nil
+}
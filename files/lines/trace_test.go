@@ -0,0 +1,55 @@
+package lines
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordTo(t *testing.T) {
+	var trace bytes.Buffer
+	if _, err := UnwrapStringWithOptions("a \\\nb\nc\n", Options{RecordTo: &trace}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []TraceEvent
+	for decoder := json.NewDecoder(&trace); decoder.More(); {
+		var event TraceEvent
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, event)
+	}
+	want := []TraceEvent{
+		{Event: "start", Text: "\\"},
+		{Event: "read", Line: 1, Text: "a \\", EOL: "\n"},
+		{Event: "connector", Line: 1, Text: "a \\"},
+		{Event: "read", Line: 2, Text: "b", EOL: "\n"},
+		{Event: "join", Line: 1, Lines: 2, Text: "a b"},
+		{Event: "read", Line: 3, Text: "c", EOL: "\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("trace = %+v, want %+v", got, want)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var trace bytes.Buffer
+	if _, err := UnwrapStringWithOptions("a \\\nb\nc\n", Options{RecordTo: &trace}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Replay(bytes.NewReader(trace.Bytes())); err != nil {
+		t.Errorf("replaying a recorded trace: %v", err)
+	}
+
+	//a join a fresh run doesn't do
+	changed := strings.Replace(trace.String(), `"text":"a b"`, `"text":"a  b"`, 1)
+	if err := Replay(strings.NewReader(changed)); err == nil {
+		t.Error("replaying a changed trace succeeded")
+	}
+	if err := Replay(strings.NewReader("")); err == nil {
+		t.Error("replaying an empty trace succeeded")
+	}
+}
@@ -2,18 +2,26 @@
 package lines
 
 import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
-
-	log "github.com/google/logger"
+	"unicode"
+	"unicode/utf8"
 )
 
 const wrap = "\\"
 
+//runChunk is how many physical lines are allocated at once for wrap runs, see flush
+const runChunk = 64
+
 //Unwrap allows me to wrap long lines into more readable shorted lines
 //Example, instead of:
 //1 {{- range key, value := zip (keys			"Rat"		"Pig"					"Monkey"			"Horse")		(values		$.HR		$.TeamLead		$.Marketing		$.Dev)
@@ -27,107 +35,811 @@ const wrap = "\\"
 //4 {{- end}}
 //and "Unwrap" my templates before parsing them
 //*Unrapping keeps line numbers
+//*Unwrapped content ends with a line ending exactly when the file does, an empty file stays empty.
+//Without one, blank lines left after a joined last line are dropped
+//*Unwrapping unwrapped content changes nothing, connectors left at the end of a logical line are stripped
 //Returns: path to a temp file with unwrapped content
-//				 function to clean up temp files
+//				 function to clean up temp files, calling it again does nothing
 //				 error if something went wrong
 func Unwrap(filePath string) (newFilePath string, cleanUp func(), err error) {
+	return UnwrapWithOptions(filePath, Options{})
+}
+
+//UnwrapWithConnector works as Unwrap with connector joining lines instead of a backslash.
+//An empty connector is an error
+func UnwrapWithConnector(filePath string, connector string) (newFilePath string, cleanUp func(), err error) {
+	return UnwrapWithOptions(filePath, connectorOptions(connector))
+}
+
+//connectorOptions returns options replacing the backslash with connector,
+//comment awareness stays as it is for the backslash
+func connectorOptions(connector string) Options {
+	return Options{Connectors: []ConnectorRule{{Connector: connector, IgnoreInComments: true}}}
+}
+
+//UnwrapInDir works as Unwrap with the temp file created in tmpDir instead of the default
+//directory for temp files, see Options.TempDir
+func UnwrapInDir(filePath string, tmpDir string) (newFilePath string, cleanUp func(), err error) {
+	return UnwrapWithOptions(filePath, Options{TempDir: tmpDir})
+}
+
+//UnwrapWithOptions works as Unwrap, opts changes how the temp file is produced
+func UnwrapWithOptions(filePath string, opts Options) (newFilePath string, cleanUp func(), err error) {
+	newFilePath, removeTemp, err := unwrapFile(context.Background(), filePath, opts, nil)
+	return newFilePath, ignoreError(removeTemp), err
+}
+
+//UnwrapContext works as UnwrapWithOptions and stops with ctx.Err() as soon as ctx is done,
+//checked before every read from filePath and cutting off a blocked read when filePath supports
+//read deadlines (e.g. a pipe), e.g. to give a deadline to unwrapping a large upload.
+//The temp file of a cancelled unwrap is removed by cleanUp as usual
+func UnwrapContext(ctx context.Context, filePath string, opts Options) (newFilePath string, cleanUp func(), err error) {
+	newFilePath, removeTemp, err := unwrapFile(ctx, filePath, opts, nil)
+	return newFilePath, ignoreError(removeTemp), err
+}
+
+//unwrapFile works as UnwrapWithOptions and stats the opened source file to info when it isn't nil.
+//Reading stops with ctx.Err() once ctx is done. cleanUp is safe to call more than once, see cleanUpOnce
+func unwrapFile(ctx context.Context, filePath string, opts Options, info *os.FileInfo) (newFilePath string, cleanUp func() error, err error) {
 
-	cleanUp = func() {} //don't return nul function
+	cleanUp = noCleanUp //don't return nul function
+	defer func() {
+		cleanUp = opts.register(newFilePath, cleanUp, err)
+	}()
 
-	text, err := readFile(filePath)
+	opts = opts.forFile(filePath)
+	if err := opts.validate(); err != nil {
+		return "", cleanUp, err
+	}
+
+	file, err := opts.openFile(filePath)
 	if err != nil {
 		return "", cleanUp, err
 	}
+	defer file.Close()
+	defer watchContext(ctx, file)()
+
+	if info != nil {
+		if *info, err = file.Stat(); err != nil {
+			err = fmt.Errorf("Failed to stat file: %s: %w", filePath, err)
+			opts.logger().Warningf(err.Error())
+			return "", cleanUp, err
+		}
+	}
 
-	tmpFile, err := tempFile(filePath)
+	var in io.Reader = file
+	if ctx.Done() != nil {
+		//before textReader, sniffing a slow file must not outlast ctx
+		in = contextReader{ctx: ctx, r: in}
+	}
+	in, err = opts.textReader(filePath, in)
 	if err != nil {
 		return "", cleanUp, err
 	}
 
-	defer tmpFile.Close()
+	if opts.DeterministicTempName {
+		return cachedTempFile(ctx, filePath, file, in, opts)
+	}
 
-	cleanUp = func() {
-		os.Remove(tmpFile.Name())
+	if opts.MemFile {
+		memFile, err := memFile(filepath.Base(filePath))
+		if err == nil {
+			return memTempFile(filePath, memFile, in, opts)
+		}
+		opts.logger().Infof("Falling back to a temp file, failed to create in-memory file for %s: %v", filePath, err)
 	}
 
-	text = unwrapLinesInString(text, wrap)
+	tmpFile, err := opts.tempFile(filePath)
+	if err != nil {
+		return "", cleanUp, err
+	}
+
+	defer tmpFile.Close()
+
+	cleanUp = opts.removeOnce(tmpFile.Name())
 
-	_, err = tmpFile.WriteString(text)
+	err = opts.unwrapTo(in, tmpFile, wrap)
 
 	if err != nil {
-		message := fmt.Sprintf("Failed to write unwrapped text to: %s", tmpFile.Name())
-		log.Warningf(message)
-		return tmpFile.Name(), cleanUp, errors.New(message)
+		err = fmt.Errorf("Failed to unwrap lines to: %s: %w", tmpFile.Name(), err)
+		opts.logger().Warningf(err.Error())
+		return tmpFile.Name(), cleanUp, err
 	}
 
-	log.Infof("Successfuly unwrapped lines to temp file %s", tmpFile.Name())
+	opts.logger().Infof("Successfuly unwrapped lines to temp file %s", tmpFile.Name())
 
 	return tmpFile.Name(), cleanUp, nil
 }
 
-func readFile(filePath string) (text string, err error) {
-	in, error := os.Open(filePath)
-	if error != nil {
-		message := fmt.Sprintf("Failed to open file: %s", filePath)
-		log.Warningf(message)
-		return "", errors.New(message)
+func (opts Options) openFile(filePath string) (*os.File, error) {
+	in, err := os.Open(filePath)
+	if err != nil {
+		err = &ioError{kind: ErrOpen, message: fmt.Sprintf("Failed to open file: %s: %s", filePath, err), err: err}
+		opts.logger().Warningf(err.Error())
+		return nil, err
+	}
+	return in, nil
+}
+
+func (opts Options) readFile(filePath string) (text string, err error) {
+	in, err := opts.openFile(filePath)
+	if err != nil {
+		return "", err
 	}
 	defer in.Close()
 
-	b, error := ioutil.ReadAll(in)
-	if error != nil {
-		message := fmt.Sprintf("Failed to read from file: %s", filePath)
-		log.Warningf(message)
-		return "", errors.New(message)
+	b, err := ioutil.ReadAll(in)
+	if err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		opts.logger().Warningf(err.Error())
+		return "", err
+	}
+	if !opts.AllowBinary {
+		if err := opts.checkText(filePath, b); err != nil {
+			return "", err
+		}
 	}
 	return string(b), nil
 }
 
-func tempFile(filePath string) (tmpFile *os.File, err error) {
+func (opts Options) tempFile(filePath string) (tmpFile *os.File, err error) {
 
 	ext := filepath.Ext(filePath)
 
 	tmpFilePattern := fmt.Sprintf("%s*%s", strings.TrimSuffix(filepath.Base(filePath), ext), ext)
+	if opts.TempPattern != nil {
+		tmpFilePattern = opts.TempPattern(filePath)
+	}
 
-	tmpFile, err = ioutil.TempFile("", tmpFilePattern)
+	tmpDir := opts.tempDir(filePath)
+	if tmpDir != "" {
+		if info, err := os.Stat(tmpDir); err != nil || !info.IsDir() {
+			message := fmt.Sprintf("Temp directory doesn't exist or isn't a directory: %s", tmpDir)
+			opts.logger().Warningf(message)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", message, err)
+			}
+			return nil, errors.New(message)
+		}
+	}
+
+	tmpFile, err = ioutil.TempFile(tmpDir, tmpFilePattern)
 
 	if err != nil {
-		message := fmt.Sprintf("Failed to created a temp file: %s", tmpFilePattern)
-		log.Warningf(message)
-		return nil, errors.New(message)
+		err = &ioError{kind: ErrWrite, message: fmt.Sprintf("Failed to created a temp file: %s: %s", tmpFilePattern, err), err: err}
+		opts.logger().Warningf(err.Error())
+		return nil, err
 	}
-	log.Infof("Successfuly created temp file %s", tmpFile.Name())
+	opts.logger().Infof("Successfuly created temp file %s", tmpFile.Name())
 
 	return tmpFile, nil
 }
 
+//tempDir returns the directory for temp files of filePath, "" for the default one.
+//Text without a source file, e.g. spilled by UnwrapString, has an empty filePath
+func (opts Options) tempDir(filePath string) string {
+	if opts.TempNextToSource && filePath != "" {
+		return filepath.Dir(filePath)
+	}
+	return opts.TempDir
+}
+
+//cachedTempFile unwraps in, read from file, to a temp file named after a hash of the source path,
+//the source and opts. An existing file with that name is reused as is without unwrapping in.
+//The file is written to a random name first and renamed into place, so concurrent unwraps of
+//the same input are safe. Callers share the file, it is removed when the last of them cleans up,
+//see holdCached
+func cachedTempFile(ctx context.Context, filePath string, file *os.File, in io.Reader, opts Options) (newFilePath string, cleanUp func() error, err error) {
+
+	cleanUp = noCleanUp
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		absPath = filePath
+	}
+
+	key, known := opts.cacheKey()
+	hash := sha256.New()
+	hash.Write([]byte(absPath))
+	hash.Write([]byte{0})
+	fmt.Fprintf(hash, "%v", key)
+	hash.Write([]byte{0})
+	if _, err := io.Copy(hash, in); err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		opts.logger().Warningf(err.Error())
+		return "", cleanUp, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		err = fmt.Errorf("Failed to read from file: %s: %w", filePath, err)
+		opts.logger().Warningf(err.Error())
+		return "", cleanUp, err
+	}
+	in = file //checked to be text by the first read
+	if ctx.Done() != nil {
+		in = contextReader{ctx: ctx, r: in}
+	}
+
+	tmpDir := opts.tempDir(filePath)
+	if tmpDir == "" {
+		tmpDir = os.TempDir()
+	}
+	ext := filepath.Ext(filePath)
+	cachedName := func() string {
+		return filepath.Join(tmpDir, fmt.Sprintf("%s-%x%s", strings.TrimSuffix(filepath.Base(filePath), ext), hash.Sum(nil)[:16], ext))
+	}
+
+	name := cachedName()
+	if known {
+		if cleanUp, ok := opts.reuseCached(name); ok {
+			opts.logger().Infof("Reusing unwrapped temp file %s", name)
+			return name, cleanUp, nil
+		}
+	}
+
+	tmpFile, err := opts.tempFile(filePath)
+	if err != nil {
+		return "", cleanUp, err
+	}
+
+	var out io.Writer = tmpFile
+	if !known {
+		//what callbacks of opts change is only known from the unwrapped text
+		out = io.MultiWriter(tmpFile, hash)
+	}
+	err = opts.unwrapTo(in, out, wrap)
+	if closeErr := tmpFile.Close(); err == nil && closeErr != nil {
+		err = writeError(closeErr)
+	}
+	name = cachedName()
+
+	reused := false
+	if err == nil {
+		reused, cleanUp, err = opts.placeCached(tmpFile.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		err = fmt.Errorf("Failed to unwrap lines to: %s: %w", name, err)
+		opts.logger().Warningf(err.Error())
+		return "", cleanUp, err
+	}
+
+	if reused {
+		opts.logger().Infof("Reusing unwrapped temp file %s", name)
+	} else {
+		opts.logger().Infof("Successfuly unwrapped lines to temp file %s", name)
+	}
+
+	return name, cleanUp, nil
+}
+
+//memTempFile unwraps in to an in-memory file, the file lives until cleanUp closes it
+func memTempFile(filePath string, memFile *os.File, in io.Reader, opts Options) (newFilePath string, cleanUp func() error, err error) {
+
+	if err = opts.unwrapTo(in, memFile, wrap); err != nil {
+		memFile.Close()
+		err = fmt.Errorf("Failed to unwrap lines to in-memory file for: %s: %w", filePath, err)
+		opts.logger().Warningf(err.Error())
+		return "", noCleanUp, err
+	}
+
+	newFilePath = fmt.Sprintf("/proc/self/fd/%d", memFile.Fd())
+	opts.logger().Infof("Successfuly unwrapped lines to in-memory file %s", newFilePath)
+
+	return newFilePath, cleanUpOnce(memFile.Close), nil
+}
+
+//UnwrapString unwraps text in memory as Unwrap unwraps a file, no files are involved
+func UnwrapString(text string) string {
+	return unwrapLinesInString(text, wrap)
+}
+
+//UnwrapStringWithConnector works as UnwrapString with connector joining lines instead of
+//a backslash. An empty connector is an error
+func UnwrapStringWithConnector(text string, connector string) (string, error) {
+	return connectorOptions(connector).unwrapText(text)
+}
+
+//UnwrapStringWithOptions works as UnwrapString, opts changes how lines are unwrapped
+func UnwrapStringWithOptions(text string, opts Options) (string, error) {
+	return opts.unwrapText(text)
+}
+
+//UnwrapWithConnectors works as UnwrapString with a line joined when it ends with any of
+//connectors, text from several tools with different connectors is unwrapped in one pass.
+//The longest matching connector wins and is the only one stripped, e.g. "\\" before "\".
+//Empty connectors are skipped, without connectors text is unwrapped as UnwrapString does
+func UnwrapWithConnectors(text string, connectors ...string) string {
+	var opts Options
+	for _, connector := range connectors {
+		if connector != "" {
+			opts.Connectors = append(opts.Connectors, ConnectorRule{Connector: connector, IgnoreInComments: true})
+		}
+	}
+
+	text, _ = opts.unwrapLines(text, wrap)
+	return text
+}
+
+//UnwrapFunc works as UnwrapString and replaces every logical line with what fn returns for it.
+//fn gets the number of the source line the logical line starts on (starting with 1), it isn't
+//called for blank lines left in place of joined lines. A nil fn unwraps as UnwrapString does
+func UnwrapFunc(text string, fn func(lineNum int, line string) string) string {
+	opts := Options{}
+	var out strings.Builder
+	u := opts.newUnwrapper(wrap, func(line logicalLine) error {
+		if fn != nil {
+			line.text = fn(line.first, line.text)
+		}
+		return opts.writeLine(&out, line)
+	})
+	forEachLine(text, u.add)
+	u.close()
+	return out.String()
+}
+
+//UnwrapBytes works as UnwrapString for a byte slice
+func UnwrapBytes(b []byte) []byte {
+	return []byte(UnwrapString(string(b)))
+}
+
+//IsUnwrapped tells if text has no connectors left that unwrapping would act on, e.g. to skip
+//files unwrapped already. Unwrapping is idempotent: IsUnwrapped(UnwrapString(text)) holds and
+//unwrapping unwrapped text changes nothing. Text unwrapped with Options.EscapeDoubledConnector
+//may end lines with literal connectors, they wrap when unwrapped again
+func IsUnwrapped(text string) bool {
+	unwrapped := true
+	u := Options{}.newUnwrapper(wrap, func(line logicalLine) error {
+		for _, physical := range line.lines {
+			unwrapped = unwrapped && physical.connector == ""
+		}
+		return nil
+	})
+	forEachLine(text, u.add)
+	u.close()
+	return unwrapped
+}
+
 func unwrapLinesInString(text string, connector string) string {
-	lines := strings.Split(text, "\n")
+	text, _ = Options{}.unwrapLines(text, connector)
+	return text
+}
 
-	for n := range lines {
-		lines[n] = strings.TrimRight(lines[n], " \r\n\t")
-		if strings.HasSuffix(lines[n], connector) {
+//physicalLine is a line as it is in the source, eol is "" for the last line without a line ending
+type physicalLine struct {
+	text       string
+	eol        string
+	connector  string //the active connector the line ends with, "" when it doesn't wrap
+	openParens bool   //the line doesn't wrap but leaves parentheses open, see Options.BalanceParens
+	escaped    bool   //the line ends with literal connectors of escaped ones, see unescapeConnectors
+}
 
-			if n >= len(lines)-1 { //trim connector from last line
-				lines[n] = strings.TrimSuffix(lines[n], connector)
-				return strings.Join(lines, "\n")
-			}
+//logicalLine is a wrap run joined into one line, lines are the physical lines of the run
+type logicalLine struct {
+	text  string
+	first int //number of the first physical line, starting with 1
+	lines []physicalLine
+	spill *os.File //holds the text instead when the run was spilled, see spill.go
+
+	verbatim bool //written as in the source, before Options.StartMarker or with Options.SkipPrefix
+}
+
+//forEachLine calls fn for every physical line in text
+func forEachLine(text string, fn func(line physicalLine) error) error {
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		if err := fn(newPhysicalLine(text[:i])); err != nil {
+			return err
+		}
+		text = text[i:]
+	}
+	return nil
+}
+
+//newPhysicalLine splits the line ending off text
+func newPhysicalLine(text string) physicalLine {
+	switch {
+	case strings.HasSuffix(text, "\r\n"):
+		return physicalLine{text: text[:len(text)-2], eol: "\r\n"}
+	case strings.HasSuffix(text, "\n"):
+		return physicalLine{text: text[:len(text)-1], eol: "\n"}
+	}
+	return physicalLine{text: text}
+}
+
+//unwrapper collects wrap runs of physical lines and emits them as logical lines
+type unwrapper struct {
+	opts      Options
+	connector string
+	rules     []ConnectorRule //longest connector first
+	emit      func(line logicalLine) error
 
-			first, next, last := n, n+1, n
-			for current := first; strings.HasSuffix(lines[current], connector); {
-				lines[next] = strings.TrimRight(lines[next], " \r\n\t")
-				current, next, last = current+1, next+1, last+1
+	lineNo  int
+	run     logicalLine
+	started bool //Options.StartMarker was found
+
+	recorder *json.Encoder //Options.RecordTo, see trace.go
+	openTag  string        //closing delimiter of an Options.Engine tag open at the end of the last line
+
+	//SpillThreshold state, see spill.go
+	runSize     int64
+	spillFile   *os.File
+	spillOut    *bufio.Writer
+	spillFailed bool
+
+	//ParagraphMode state, see paragraph.go
+	held           *logicalLine
+	joinParagraphs bool
+
+	parenDepth int //parentheses open at the end of the last line, see Options.BalanceParens
+}
+
+func (opts Options) newUnwrapper(connector string, emit func(line logicalLine) error) *unwrapper {
+	u := &unwrapper{opts: opts, connector: connector, emit: emit, rules: opts.connectorRules(connector)}
+	if opts.RecordTo != nil {
+		u.recorder = json.NewEncoder(opts.RecordTo)
+	}
+	return u
+}
+
+//add takes the next physical line
+func (u *unwrapper) add(line physicalLine) error {
+	u.lineNo++
+	if u.lineNo == 1 {
+		if err := u.trace(TraceEvent{Event: "start", Text: u.connector}); err != nil {
+			return err
+		}
+	}
+	if err := u.trace(TraceEvent{Event: "read", Line: u.lineNo, Text: line.text, EOL: line.eol}); err != nil {
+		return err
+	}
+	if u.lineNo == 1 && !u.opts.PreserveBOM {
+		line.text = strings.TrimLeft(line.text, utf8BOM)
+	}
+	if u.opts.MaxPhysicalLineLength > 0 && len(line.text) > u.opts.MaxPhysicalLineLength {
+		return u.opts.lineTooLong(u.lineNo)
+	}
+	if u.opts.StartMarker != "" && !u.started {
+		return u.preamble(line)
+	}
+	if u.opts.SkipPrefix != "" && strings.HasPrefix(u.trimLeft(line.text), u.opts.SkipPrefix) {
+		return u.skip(line)
+	}
+
+	//connectors are looked for in the trimmed text, lines are only trimmed when they are joined
+	text := u.trimRight(line.text)
+
+	if u.opts.ParagraphMode && len(u.run.lines) == 0 && u.held != nil && u.isJoint(text) {
+		u.joinParagraph(line)
+		return nil
+	}
+
+	if u.brokenPair(text) {
+		if err := u.flush(); err != nil {
+			return err
+		}
+	}
+
+	unescaped := false
+	if tags := engineTags[u.opts.Engine]; tags != nil {
+		u.openTag = scanTags(text, tags, u.openTag)
+	}
+	if u.openTag == "" {
+		line.connector = u.continues(text)
+		if u.opts.EscapeDoubledConnector {
+			before := text
+			text, line.connector = unescapeConnectors(text, line.connector)
+			unescaped = text != before
+			line.escaped = unescaped
+		}
+	}
+	if u.opts.BalanceParens {
+		u.parenDepth = u.countParens(text, u.parenDepth)
+		line.openParens = line.connector == "" && u.parenDepth > 0
+	}
+	if u.opts.TrimUnjoinedLines || unescaped || line.connector != "" || line.openParens || len(u.run.lines) > 0 {
+		line.text = text
+	}
+
+	if len(u.run.lines) == 0 {
+		u.run.first = u.lineNo
+		if cap(u.run.lines) == 0 {
+			u.run.lines = make([]physicalLine, 0, runChunk)
+		}
+	}
+	u.run.lines = append(u.run.lines, line)
+	if u.opts.MaxContinuations > 0 && len(u.run.lines) > u.opts.MaxContinuations {
+		return u.opts.lineError(u.run.first, fmt.Errorf("%w: joins more than %d lines", ErrTooManyContinuations, u.opts.MaxContinuations))
+	}
+	if err := u.spill(); err != nil {
+		return err
+	}
+
+	if (line.connector != "" || line.openParens) && line.eol != "" {
+		return u.trace(TraceEvent{Event: "connector", Line: u.lineNo, Text: line.text})
+	}
+	if err := u.checkDangling(); err != nil {
+		return err
+	}
+	return u.flush()
+}
+
+//preamble emits a line before Options.StartMarker as it is
+func (u *unwrapper) preamble(line physicalLine) error {
+	if strings.TrimSpace(line.text) == u.opts.StartMarker {
+		u.started = true
+		if u.opts.ConsumeStartMarker {
+			line.text = ""
+		}
+	}
+	if u.opts.TrimAllTrailingWhitespace {
+		line.text = u.trimRight(line.text)
+	}
+	return u.emit(logicalLine{text: line.text, first: u.lineNo, lines: []physicalLine{line}, verbatim: true})
+}
+
+//skip ends the current wrap run and emits line as it is, see Options.SkipPrefix
+func (u *unwrapper) skip(line physicalLine) error {
+	if err := u.flush(); err != nil {
+		return err
+	}
+	if err := u.release(); err != nil {
+		return err
+	}
+	return u.emit(logicalLine{text: line.text, first: u.lineNo, lines: []physicalLine{line}, verbatim: true})
+}
+
+//close flushes a wrap run left open by a connector on the last line
+func (u *unwrapper) close() error {
+	if err := u.checkDangling(); err != nil {
+		return err
+	}
+	if err := u.flush(); err != nil {
+		return err
+	}
+	return u.release()
+}
+
+func (u *unwrapper) flush() error {
+	if len(u.run.lines) == 0 {
+		return nil
+	}
+	//the next run takes the rest of the array instead of a new one, run.lines is capped so
+	//appending to it elsewhere (e.g. hold) never overwrites the next run
+	run := u.run
+	run.lines = run.lines[:len(run.lines):len(run.lines)]
+	u.run = logicalLine{lines: u.run.lines[len(u.run.lines):]}
+	u.runSize = 0
+
+	if u.opts.ContinuationPrefix != "" {
+		//the last line of a run never joined the next one, see brokenPair
+		run.lines[len(run.lines)-1].connector = ""
+	}
+
+	if u.spillFile != nil {
+		return u.sendSpilled(run)
+	}
+
+	if u.opts.MaxCollapseRun > 0 && len(run.lines) > u.opts.MaxCollapseRun {
+		for i, line := range run.lines {
+			if err := u.send(logicalLine{text: line.text, first: run.first + i, lines: run.lines[i : i+1]}); err != nil {
+				return err
 			}
+		}
+		return nil
+	}
+
+	if len(run.lines) == 1 {
+		run.text = u.stripConnectors(run, u.segment(run.lines, 0))
+		return u.send(run)
+	}
+
+	var lineBuilder strings.Builder
+	lineBuilder.WriteString(u.segment(run.lines, 0))
+	for i := range run.lines[1:] {
+		segment := u.segment(run.lines, i+1)
+		if u.opts.WarnOnTokenMerge {
+			u.warnOnTokenMerge(lineBuilder.String(), segment, run.first+i+1)
+		}
+		lineBuilder.WriteString(segment)
+	}
+	run.text = u.stripConnectors(run, lineBuilder.String())
+	if run.first == 1 && !u.opts.PreserveBOM {
+		//a joined line may start with a byte order mark of the line after the first one
+		run.text = strings.TrimLeft(run.text, utf8BOM)
+	}
+	if u.opts.ExpandTabs > 0 {
+		run.text = expandTabs(run.text, u.opts.ExpandTabs)
+	}
+
+	if err := u.trace(TraceEvent{Event: "join", Line: run.first, Lines: len(run.lines), Text: run.text}); err != nil {
+		return err
+	}
+	return u.send(run)
+}
+
+//segment returns what the n-th line of wrap run lines adds to the joined line
+func (u *unwrapper) segment(lines []physicalLine, n int) string {
+	line := lines[n]
+	text := line.text
+	if line.connector != "" {
+		text = strings.TrimSuffix(text, line.connector)
+		if u.opts.OnStripConnector != nil {
+			text = u.opts.OnStripConnector(text)
+		}
+		if u.opts.Join == JoinSpace {
+			text = u.trimRight(text)
+		}
+	}
+	if n == 0 {
+		return text
+	}
+	trimmed := u.trimLeft(text)
+	indent := text[:len(text)-len(trimmed)]
+	if u.opts.ContinuationPrefix != "" && lines[n-1].connector != "" {
+		trimmed = strings.TrimPrefix(trimmed, u.opts.ContinuationPrefix)
+	}
+	switch {
+	case u.opts.Join == JoinSpace, lines[n-1].openParens && u.opts.NewlineReplacement == "":
+		return " " + u.trimLeft(trimmed)
+	case u.opts.Join == JoinPreserve, u.opts.IndentPolicy == KeepOriginal:
+		return u.opts.NewlineReplacement + indent + trimmed
+	case u.opts.IndentPolicy == KeepOne && indent != "":
+		return u.opts.NewlineReplacement + " " + trimmed
+	}
+	return u.opts.NewlineReplacement + trimmed
+}
+
+//warnOnTokenMerge logs a warning when joining segment to joined glues two words into one
+func (u *unwrapper) warnOnTokenMerge(joined string, segment string, lineNo int) {
+	before, _ := utf8.DecodeLastRuneInString(joined)
+	after, _ := utf8.DecodeRuneInString(segment)
+	if !isWordRune(before) || !isWordRune(after) {
+		return
+	}
+	start := strings.LastIndexFunc(joined, func(r rune) bool { return !isWordRune(r) }) + 1
+	end := strings.IndexFunc(segment, func(r rune) bool { return !isWordRune(r) })
+	if end < 0 {
+		end = len(segment)
+	}
+	u.opts.logger().Warningf("Joining line %d merges tokens into %q", lineNo, joined[start:]+segment[:end])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+//send passes a complete logical line on to emit
+func (u *unwrapper) send(line logicalLine) error {
+	if u.opts.ForceIndent > 0 && line.spill == nil && (len(line.lines) > 1 || u.opts.ForceIndentAll) {
+		line.text = strings.Repeat(" ", u.opts.ForceIndent) + u.trimLeft(line.text)
+	}
+	if u.opts.TrimAllTrailingWhitespace && line.spill == nil {
+		line.text = u.trimRight(line.text)
+	}
+	if u.opts.ValidateUTF8 && !utf8.ValidString(line.text) {
+		return u.opts.lineError(line.first, ErrInvalidUTF8)
+	}
+	if u.opts.ParagraphMode {
+		return u.hold(line)
+	}
+	return u.emit(line)
+}
+
+//trimRight trims trailing whitespace of a physical line
+func (u *unwrapper) trimRight(text string) string {
+	trim := func(text string) string { return strings.TrimRight(text, " \r\n\t") }
+	if u.opts.UnicodeWhitespace {
+		trim = func(text string) string { return strings.TrimRightFunc(text, unicode.IsSpace) }
+	}
+	if u.opts.OpaqueByteOrderMarks {
+		return trimRightKeepingMarks(text, trim)
+	}
+	return trim(text)
+}
+
+//trimLeft trims leading whitespace of a continuation line
+func (u *unwrapper) trimLeft(text string) string {
+	trim := func(text string) string { return strings.TrimLeft(text, " \t") }
+	if u.opts.UnicodeWhitespace {
+		trim = func(text string) string { return strings.TrimLeftFunc(text, unicode.IsSpace) }
+	}
+	if u.opts.OpaqueByteOrderMarks {
+		return trimLeftKeepingMarks(text, trim)
+	}
+	return trim(text)
+}
+
+//unwrapLines unwraps text using connector, every joined line is followed
+//by blank lines in place of the lines it consumed so line numbers are kept
+func (opts Options) unwrapLines(text string, connector string) (string, error) {
+	opts = opts.dominantLineEnding(text)
+	var out strings.Builder
+	out.Grow(len(text))
 
-			var lineBuilder strings.Builder
-			lineBuilder.WriteString(strings.TrimSuffix(lines[first], connector))
-			for i := first + 1; i <= last; i++ {
-				lineBuilder.WriteString(strings.TrimLeft(strings.TrimSuffix(lines[i], connector), " \t"))
-				lines[i] = ""
+	u := opts.newUnwrapper(connector, func(line logicalLine) error {
+		return opts.writeLine(&out, line)
+	})
+	if err := forEachLine(text, u.add); err != nil {
+		u.abort()
+		return "", err
+	}
+	if err := u.close(); err != nil {
+		u.abort()
+		return "", err
+	}
+	return out.String(), nil
+}
+
+//lineWriter is where unwrapped lines are written to
+type lineWriter interface {
+	io.Writer
+	io.StringWriter
+}
+
+//writeLine writes line and the line endings of all physical lines it was joined from
+func (opts Options) writeLine(w lineWriter, line logicalLine) error {
+	if opts.LineDirectives != "" {
+		if err := opts.writeLineDirective(w, line.first); err != nil {
+			if line.spill != nil {
+				removeSpilled(line.spill)
 			}
-			lines[first] = lineBuilder.String()
-			lineBuilder.Reset()
+			return err
+		}
+	}
+	eols := line.lines
+	if line.spill != nil {
+		if err := copySpilled(w, line.spill); err != nil {
+			return err
+		}
+	} else if opts.MaxOutLine > 0 && !line.verbatim && len(line.text) > opts.MaxOutLine {
+		var err error
+		if eols, err = opts.writeBroken(w, line); err != nil {
+			return err
+		}
+	} else if _, err := w.WriteString(opts.trimCR(line)); err != nil {
+		return err
+	}
+	if n := len(eols); n > 0 && eols[n-1].eol == "" {
+		//the source ends without a line ending, so does the unwrapped text: padding after
+		//the last line would end it with one
+		eols = nil
+	}
+	for _, physical := range eols {
+		eol := physical.eol
+		if !line.verbatim || opts.ForceLineEnding != "" {
+			eol = opts.lineEnding(eol)
+		}
+		if _, err := w.WriteString(eol); err != nil {
+			return err
 		}
 	}
-	return strings.Join(lines, "\n")
+	return nil
+}
+
+//trimCR trims carriage returns off the end of line when the line ending written after it
+//is "\n", so they never form a "\r\n" that isn't in the source
+func (opts Options) trimCR(line logicalLine) string {
+	if len(line.lines) == 0 || line.verbatim && opts.ForceLineEnding == "" {
+		return line.text
+	}
+	if eol := opts.lineEnding(line.lines[0].eol); strings.HasPrefix(eol, "\n") {
+		return strings.TrimRight(line.text, "\r")
+	}
+	return line.text
+}
+
+func (opts Options) lineEnding(eol string) string {
+	if eol == "" {
+		return ""
+	}
+	if opts.ForceLineEnding != "" {
+		return opts.ForceLineEnding
+	}
+	if opts.PreserveLineEndings {
+		return eol
+	}
+	return "\n"
 }
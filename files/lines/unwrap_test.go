@@ -0,0 +1,782 @@
+package lines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//writeTemp writes text to a file named name in a temp directory removed when t ends
+func writeTemp(t *testing.T, name string, text string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(filePath, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestDeterministicTempName(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	opts := Options{DeterministicTempName: true, TempDir: t.TempDir()}
+
+	first, cleanUp1, err := UnwrapWithOptions(filePath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, cleanUp2, err := UnwrapWithOptions(filePath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatalf("unwraps of the same file got %s and %s", first, second)
+	}
+	if b, err := ioutil.ReadFile(first); err != nil || string(b) != "a b\n\n" {
+		t.Fatalf("cached file has %q, %v", b, err)
+	}
+
+	cleanUp1()
+	if _, err := os.Stat(second); err != nil {
+		t.Fatalf("cached file removed while still held: %v", err)
+	}
+	cleanUp2()
+	if _, err := os.Stat(second); !os.IsNotExist(err) {
+		t.Fatalf("cached file kept after the last clean up: %v", err)
+	}
+}
+
+func TestDeterministicTempNameReuse(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	opts := Options{DeterministicTempName: true, TempDir: t.TempDir()}
+
+	first, cleanUp1, err := UnwrapWithOptions(filePath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp1()
+
+	var trace bytes.Buffer
+	opts.RecordTo = &trace
+	second, cleanUp2, err := UnwrapWithOptions(filePath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp2()
+	if second != first || trace.Len() > 0 {
+		t.Errorf("reusing %s unwrapped to %s again: %s", first, second, trace.String())
+	}
+
+	opts.RecordTo = nil
+	opts.Join = JoinSpace
+	third, cleanUp3, err := UnwrapWithOptions(filePath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp3()
+	if third == first {
+		t.Errorf("unwrap with other options reused %s", first)
+	}
+}
+
+func TestDeterministicTempNameContent(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	opts := Options{DeterministicTempName: true, TempDir: t.TempDir()}
+
+	first, cleanUp1, err := UnwrapWithOptions(filePath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp1()
+
+	if err := ioutil.WriteFile(filePath, []byte("c \\\nd\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second, cleanUp2, err := UnwrapWithOptions(filePath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp2()
+	if second == first {
+		t.Fatalf("changed file reused %s", first)
+	}
+	if b, err := ioutil.ReadFile(second); err != nil || string(b) != "c d\n\n" {
+		t.Errorf("cached file of the changed file has %q, %v", b, err)
+	}
+}
+
+func TestDeterministicTempNameConcurrent(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", strings.Repeat("a \\\nb\n", 1000))
+	tmpDir := t.TempDir()
+	opts := Options{DeterministicTempName: true, TempDir: tmpDir}
+
+	const n = 8
+	paths := make([]string, n)
+	cleanUps := make([]func(), n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paths[i], cleanUps[i], errs[i] = UnwrapWithOptions(filePath, opts)
+		}(i)
+	}
+	wg.Wait()
+
+	want := strings.Repeat("a b\n\n", 1000)
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if paths[i] != paths[0] {
+			t.Errorf("concurrent unwraps got %s and %s", paths[0], paths[i])
+		}
+	}
+	if b, err := ioutil.ReadFile(paths[0]); err != nil || string(b) != want {
+		t.Errorf("cached file has %d bytes, %v, want %d bytes", len(b), err, len(want))
+	}
+
+	for i := 0; i < n-1; i++ {
+		cleanUps[i]()
+	}
+	if _, err := os.Stat(paths[0]); err != nil {
+		t.Fatalf("cached file removed while still held: %v", err)
+	}
+	cleanUps[n-1]()
+	if infos, err := ioutil.ReadDir(tmpDir); err != nil || len(infos) > 0 {
+		t.Errorf("clean ups left %d files, %v", len(infos), err)
+	}
+}
+
+func TestUnwrapStringTrailingNewline(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"a", "a"},
+		{"a\n", "a\n"},
+		{"a\nb", "a\nb"},
+		{"a\\\nb", "ab"},
+		{"a\\\nb\n", "ab\n\n"},
+		{"a\\\nb\\\nc", "abc"},
+		{"a\\\n", "a\n"},
+		{"a\\", "a"},
+		{"a\r\nb\\\r\nc", "a\nbc"},
+	}
+	for _, test := range tests {
+		if got := UnwrapString(test.text); got != test.want {
+			t.Errorf("UnwrapString(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestUnwrapTrailingNewline(t *testing.T) {
+	for _, text := range []string{"a\\\nb", "a\\\nb\n"} {
+		newFilePath, cleanUp, err := UnwrapWithOptions(writeTemp(t, "a.txt", text), Options{TempDir: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(newFilePath)
+		cleanUp()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := UnwrapString(text); string(b) != want {
+			t.Errorf("unwrapped %q to %q, want %q", text, b, want)
+		}
+	}
+}
+
+func BenchmarkUnwrap(b *testing.B) {
+	text := strings.Repeat("{{ if .Values.enabled }} \\\n   value: {{ .Values.x }}\nplain line here\n\n", 5000)
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		UnwrapString(text)
+	}
+}
+
+func BenchmarkUnwrapFile(b *testing.B) {
+	filePath := filepath.Join(b.TempDir(), "bench.tmpl")
+	text := strings.Repeat("{{ if .Values.enabled }} \\\n   value: {{ .Values.x }}\nplain line here\n\n", 5000)
+	if err := ioutil.WriteFile(filePath, []byte(text), 0644); err != nil {
+		b.Fatal(err)
+	}
+	opts := Options{TempDir: b.TempDir(), Logger: &recordingLogger{}}
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, cleanUp, err := UnwrapWithOptions(filePath, opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cleanUp()
+	}
+}
+
+func TestSkipPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"skipped line", "# noexpand a \\\nb \\\nc\n", "# noexpand a \\\nb c\n\n"},
+		{"indented skipped line", "  # noexpand a \\\nb\n", "  # noexpand a \\\nb\n"},
+		{"ends a wrap run", "a \\\n# noexpand b \\\nc\n", "a \n# noexpand b \\\nc\n"},
+		{"other lines", "# a \\\nb\n", "# a b\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{SkipPrefix: "# noexpand"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapIdempotent(t *testing.T) {
+	tests := []string{
+		"a \\\nb\n",
+		"a \\\n\\\nb\n",
+		"a \\\\\nb\n",
+		"a\\\\\nb\n",
+		"a \\\n",
+		"a \\",
+		"a\r\\\r\nb\r\n",
+		"\uFEFF\uFEFFa \\\n\uFEFFb\n",
+		"a \\\n\n\\\n",
+		"# comment \\\nb\n",
+		"a\r\r\nb\n",
+	}
+	for _, text := range tests {
+		once := UnwrapString(text)
+		if twice := UnwrapString(once); twice != once {
+			t.Errorf("unwrapping %q again changed %q to %q", text, once, twice)
+		}
+		if !IsUnwrapped(once) {
+			t.Errorf("IsUnwrapped(%q) = false, unwrapped from %q", once, text)
+		}
+	}
+}
+
+func TestConnectorEscape(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		escape string //unwrapped with Options.EscapeDoubledConnector
+	}{
+		{"one backslash", "a \\\nb\n", "a b\n\n", "a b\n\n"},
+		{"two backslashes", "a \\\\\nb\n", "a \\b\n\n", "a \\\nb\n"},
+		{"three backslashes", "a \\\\\\\nb\n", "a \\\\b\n\n", "a \\b\n\n"},
+		{"four backslashes", "a \\\\\\\\\nb\n", "a \\\\\\b\n\n", "a \\\\\nb\n"},
+		{"last line", "a \\\\", "a ", "a \\"},
+		{"inside the line", "a \\\\ b\n", "a \\\\ b\n", "a \\\\ b\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapString(test.text); got != test.want {
+				t.Errorf("UnwrapString(%q) = %q, want %q", test.text, got, test.want)
+			}
+			got, err := UnwrapStringWithOptions(test.text, Options{EscapeDoubledConnector: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.escape {
+				t.Errorf("with EscapeDoubledConnector unwrapped %q to %q, want %q", test.text, got, test.escape)
+			}
+		})
+	}
+}
+
+func TestIsUnwrapped(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"", true},
+		{"a\nb\n", true},
+		{"a \\\nb\n", false},
+		{"a \\", false},
+		{UnwrapString("a\\\\\nb\n"), true},
+	}
+	for _, test := range tests {
+		if got := IsUnwrapped(test.text); got != test.want {
+			t.Errorf("IsUnwrapped(%q) = %t, want %t", test.text, got, test.want)
+		}
+	}
+}
+
+func TestUnicodeWhitespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		unicode string //unwrapped with Options.UnicodeWhitespace
+	}{
+		{"ideographic spaces", "a\u3000\\\n\u3000b\n", "a\u3000\u3000b\n\n", "a\u3000b\n\n"},
+		{"no-break spaces", "a\u00A0\\\n\u00A0b\n", "a\u00A0\u00A0b\n\n", "a\u00A0b\n\n"},
+		{"trailing white space", "a \\\n\u3000b\u3000\n", "a \u3000b\u3000\n\n", "a b\n\n"},
+		{"unjoined line", "a\u3000\nb\n", "a\u3000\nb\n", "a\u3000\nb\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapString(test.text); got != test.want {
+				t.Errorf("UnwrapString(%q) = %q, want %q", test.text, got, test.want)
+			}
+			got, err := UnwrapStringWithOptions(test.text, Options{UnicodeWhitespace: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.unicode {
+				t.Errorf("with UnicodeWhitespace unwrapped %q to %q, want %q", test.text, got, test.unicode)
+			}
+		})
+	}
+}
+
+func TestWarnOnTokenMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		warning string //"" for no warning
+	}{
+		{"merged tokens", "x = foo\\\nbar\n", `W Joining line 2 merges tokens into "foobar"`},
+		{"merged digits", "a 12\\\n  34 b\n", `W Joining line 2 merges tokens into "1234"`},
+		{"space before connector", "x = foo \\\nbar\n", ""},
+		{"punctuation", "f(a,\\\nb)\n", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			if _, err := UnwrapStringWithOptions(test.text, Options{WarnOnTokenMerge: true, Logger: logger}); err != nil {
+				t.Fatal(err)
+			}
+			var warnings []string
+			for _, message := range logger.messages {
+				if strings.HasPrefix(message, "W ") {
+					warnings = append(warnings, message)
+				}
+			}
+			switch {
+			case test.warning == "" && len(warnings) > 0:
+				t.Errorf("safe join warned %q", warnings)
+			case test.warning != "" && (len(warnings) != 1 || warnings[0] != test.warning):
+				t.Errorf("got warnings %q, want %q", warnings, test.warning)
+			}
+		})
+	}
+}
+
+func TestOnStripConnector(t *testing.T) {
+	dropComma := func(beforeConnector string) string {
+		return strings.TrimSuffix(strings.TrimRight(beforeConnector, " "), ",") + " "
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"trailing comma dropped", "f(a, \\\n  b)\n", "f(a b)\n\n"},
+		{"no comma", "f(a \\\n  b)\n", "f(a b)\n\n"},
+		{"unjoined line kept", "f(a,\nb)\n", "f(a,\nb)\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{OnStripConnector: dropComma})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestForceIndent(t *testing.T) {
+	text := "    a \\\n  b\n        c\n"
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"joined lines", Options{ForceIndent: 2}, "  a b\n\n        c\n"},
+		{"all lines", Options{ForceIndent: 2, ForceIndentAll: true}, "  a b\n\n  c\n"},
+		{"off", Options{}, "    a b\n\n        c\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", text, got, test.want)
+			}
+		})
+	}
+
+	if _, err := UnwrapStringWithOptions(text, Options{ForceIndent: -1}); err == nil {
+		t.Error("negative ForceIndent got no error")
+	}
+}
+
+func TestNewlineReplacement(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		replacement string
+		want        string
+	}{
+		{"JSON escape", "a\\\nb\\\nc\n", `\n`, "a\\nb\\nc\n\n\n"},
+		{"statements", "x = 1\\\n  y = 2\n", "; ", "x = 1; y = 2\n\n"},
+		{"unjoined lines", "a\nb\n", "; ", "a\nb\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(test.text, Options{NewlineReplacement: test.replacement})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+
+	if _, err := UnwrapStringWithOptions("a\n", Options{NewlineReplacement: "; ", Join: JoinSpace}); err == nil {
+		t.Error("NewlineReplacement with JoinSpace got no error")
+	}
+}
+
+func TestStartMarker(t *testing.T) {
+	text := "license \\\nheader\n---\na \\\nb\n"
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"marker kept", Options{StartMarker: "---"}, "license \\\nheader\n---\na b\n\n"},
+		{"marker consumed", Options{StartMarker: "---", ConsumeStartMarker: true}, "license \\\nheader\n\na b\n\n"},
+		{"no marker line", Options{StartMarker: "+++"}, text},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := UnwrapStringWithOptions(text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTrimAllTrailingWhitespace(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		opts Options
+		want string
+	}{
+		{"all lines", "a \t\nb \\\n  c  \nd\r\n", Options{}, "a\nb c\n\nd\n"},
+		{"before start marker", "a  \n---\nb \\\nc \n", Options{StartMarker: "---"}, "a\n---\nb c\n\n"},
+		{"connector left before white space", "a \\\n\nb\n", Options{}, "a\n\nb\n"},
+		{"line endings kept", "a \r\nb\t\r\n", Options{PreserveLineEndings: true}, "a\r\nb\r\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.TrimAllTrailingWhitespace = true
+			got, err := UnwrapStringWithOptions(test.text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("UnwrapStringWithOptions(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnwrapMatchesUnwrapString(t *testing.T) {
+	tests := []string{
+		"",
+		"a\n",
+		"a \\\nb\nc\n",
+		"a \\\n  b \\\n  c\n",
+		"a\r\nb \\\r\nc\r\n",
+		"a \\\\\nb\n",
+		"\uFEFFa \\\nb",
+	}
+	for _, text := range tests {
+		newFilePath, cleanUp, err := UnwrapWithOptions(writeTemp(t, "a.txt", text), Options{TempDir: t.TempDir()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadFile(newFilePath)
+		cleanUp()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := UnwrapString(text); string(b) != want {
+			t.Errorf("Unwrap unwrapped %q to %q, UnwrapString to %q", text, b, want)
+		}
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		preserve string //unwrapped with Options.PreserveLineEndings
+		dominant string //unwrapped with Options.DominantLineEnding
+	}{
+		{"LF", "a \\\nb\nc\n", "a b\n\nc\n", "a b\n\nc\n", "a b\n\nc\n"},
+		{"CRLF", "a \\\r\nb\r\nc\r\n", "a b\n\nc\n", "a b\r\n\r\nc\r\n", "a b\r\n\r\nc\r\n"},
+		{"mixed", "a \\\r\nb\nc\r\nd\r\n", "a b\n\nc\nd\n", "a b\r\n\nc\r\nd\r\n", "a b\r\n\r\nc\r\nd\r\n"},
+		{"mixed mostly LF", "a\r\nb\nc\n", "a\nb\nc\n", "a\r\nb\nc\n", "a\nb\nc\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapString(test.text); got != test.want {
+				t.Errorf("UnwrapString(%q) = %q, want %q", test.text, got, test.want)
+			}
+			got, err := UnwrapStringWithOptions(test.text, Options{PreserveLineEndings: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.preserve {
+				t.Errorf("with PreserveLineEndings unwrapped %q to %q, want %q", test.text, got, test.preserve)
+			}
+			got, err = UnwrapStringWithOptions(test.text, Options{DominantLineEnding: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.dominant {
+				t.Errorf("with DominantLineEnding unwrapped %q to %q, want %q", test.text, got, test.dominant)
+			}
+		})
+	}
+
+	if _, err := UnwrapStringWithOptions("a\n", Options{PreserveLineEndings: true, DominantLineEnding: true}); err == nil {
+		t.Error("PreserveLineEndings with DominantLineEnding got no error")
+	}
+}
+
+func TestUnwrapInDir(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	tmpDir := t.TempDir()
+	newFilePath, cleanUp, err := UnwrapInDir(filePath, tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+	if filepath.Dir(newFilePath) != tmpDir {
+		t.Errorf("unwrapped to %s, want it in %s", newFilePath, tmpDir)
+	}
+
+	for name, dir := range map[string]string{
+		"missing directory": filepath.Join(tmpDir, "missing"),
+		"not a directory":   filePath,
+	} {
+		if _, cleanUp, err := UnwrapInDir(filePath, dir); err == nil {
+			cleanUp()
+			t.Errorf("%s got no error", name)
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		readOnly := t.TempDir()
+		if err := os.Chmod(readOnly, 0500); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chmod(readOnly, 0700)
+		if _, cleanUp, err := UnwrapInDir(filePath, readOnly); !errors.Is(err, ErrWrite) {
+			cleanUp()
+			t.Errorf("read-only directory got %v, want %v", err, ErrWrite)
+		}
+	}
+}
+
+func TestSingleSource(t *testing.T) {
+	//files/lines is the only copy of the package, a second one next to it would drift apart
+	filePaths, err := filepath.Glob(filepath.Join("..", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filePath := range filePaths {
+		b, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(b, []byte("\npackage lines\n")) || bytes.HasPrefix(b, []byte("package lines\n")) {
+			t.Errorf("%s is a second copy of package lines", filePath)
+		}
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		preserve string //unwrapped with Options.PreserveBOM
+	}{
+		{"first line", "\uFEFFa\nb\n", "a\nb\n", "\uFEFFa\nb\n"},
+		{"joined first line", "\uFEFFa \\\nb\n", "a b\n\n", "\uFEFFa b\n\n"},
+		{"only a mark", "\uFEFF", "", "\uFEFF"},
+		{"mark and line ending", "\uFEFF\n", "\n", "\uFEFF\n"},
+		{"mark inside a line", "a\uFEFF\n", "a\uFEFF\n", "a\uFEFF\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapString(test.text); got != test.want {
+				t.Errorf("UnwrapString(%q) = %q, want %q", test.text, got, test.want)
+			}
+			got, err := UnwrapStringWithOptions(test.text, Options{PreserveBOM: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.preserve {
+				t.Errorf("with PreserveBOM unwrapped %q to %q, want %q", test.text, got, test.preserve)
+			}
+
+			newFilePath, cleanUp, err := UnwrapWithOptions(writeTemp(t, "bom.txt", test.text), Options{TempDir: t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanUp()
+			if b, err := ioutil.ReadFile(newFilePath); err != nil || string(b) != test.want {
+				t.Errorf("Unwrap unwrapped %q to %q, %v, want %q", test.text, b, err, test.want)
+			}
+		})
+	}
+}
+
+func TestTrimUnjoinedLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+		trim string //unwrapped with Options.TrimUnjoinedLines
+	}{
+		{"trailing spaces", "a  \nb\t\n", "a  \nb\t\n", "a\nb\n"},
+		{"joined line", "a  \nb \\\nc  \n", "a  \nb c\n\n", "a\nb c\n\n"},
+		{"last line", "a  ", "a  ", "a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapString(test.text); got != test.want {
+				t.Errorf("UnwrapString(%q) = %q, want %q", test.text, got, test.want)
+			}
+			got, err := UnwrapStringWithOptions(test.text, Options{TrimUnjoinedLines: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.trim {
+				t.Errorf("with TrimUnjoinedLines unwrapped %q to %q, want %q", test.text, got, test.trim)
+			}
+		})
+	}
+}
+
+func TestUnwrapConnectorEscape(t *testing.T) {
+	//one, two and three backslashes in a file, as TestConnectorEscape does for strings
+	text := "a \\\nb\nc \\\\\nd\ne \\\\\\\nf\n"
+	opts := Options{EscapeDoubledConnector: true, TempDir: t.TempDir()}
+	newFilePath, cleanUp, err := UnwrapWithOptions(writeTemp(t, "a.sh", text), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanUp()
+	b, err := ioutil.ReadFile(newFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a b\n\nc \\\nd\ne \\f\n\n"; string(b) != want {
+		t.Errorf("unwrapped %q to %q, want %q", text, b, want)
+	}
+}
+
+func TestUnwrapContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts Options
+		err  error
+		want string //content of the temp file
+	}{
+		{"background", context.Background(), Options{}, nil, "a b\n\n"},
+		{"options", context.Background(), connectorOptions("&&"), nil, "a \\\nb\n"},
+		{"cancelled", cancelled, Options{}, context.Canceled, ""},
+		{"deadline exceeded", expired, Options{}, context.DeadlineExceeded, ""},
+		{"cancelled with a deterministic name", cancelled, Options{DeterministicTempName: true}, context.Canceled, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			test.opts.TempDir = tmpDir
+			newFilePath, cleanUp, err := UnwrapContext(test.ctx, writeTemp(t, "a.txt", "a \\\nb\n"), test.opts)
+			if !errors.Is(err, test.err) || (err != nil) != (test.err != nil) {
+				cleanUp()
+				t.Fatalf("got %v, want %v", err, test.err)
+			}
+			if err == nil {
+				if b, err := ioutil.ReadFile(newFilePath); err != nil || string(b) != test.want {
+					t.Errorf("temp file has %q, %v, want %q", b, err, test.want)
+				}
+			}
+
+			cleanUp()
+			if infos, err := ioutil.ReadDir(tmpDir); err != nil || len(infos) > 0 {
+				t.Errorf("cleanUp left %d temp files, %v", len(infos), err)
+			}
+		})
+	}
+}
+
+func TestUnwrapFunc(t *testing.T) {
+	numbered := func(lineNum int, line string) string {
+		return fmt.Sprintf("%d: %s", lineNum, line)
+	}
+	tests := []struct {
+		name string
+		text string
+		fn   func(lineNum int, line string) string
+		want string
+	}{
+		{"numbered", "a \\\nb\nc\n", numbered, "1: a b\n\n3: c\n"},
+		{"without last line ending", "a\nb \\\nc", numbered, "1: a\n2: b c"},
+		{"blank line of the source", "a\n\nb\n", numbered, "1: a\n2: \n3: b\n"},
+		{"upper", "a \\\nb\n", func(lineNum int, line string) string { return strings.ToUpper(line) }, "A B\n\n"},
+		{"nil", "a \\\nb\n", nil, "a b\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := UnwrapFunc(test.text, test.fn); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
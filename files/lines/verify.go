@@ -0,0 +1,51 @@
+package lines
+
+import (
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//ErrHashMismatch is returned by UnwrapVerify when unwrapped content has another hash than expected
+var ErrHashMismatch = errors.New("unwrapped content hash mismatch")
+
+//UnwrapVerify unwraps filePath in memory and checks that SHA-256 of the result
+//is expectedHash (hex encoded). Returns unwrapped content, also when hashes don't match
+func UnwrapVerify(filePath string, expectedHash string) (string, error) {
+	return UnwrapVerifyWithOptions(filePath, expectedHash, Options{})
+}
+
+//UnwrapVerifyWithOptions works as UnwrapVerify, opts.Hash picks the hash algorithm
+func UnwrapVerifyWithOptions(filePath string, expectedHash string, opts Options) (string, error) {
+	algorithm := opts.Hash
+	if algorithm == 0 {
+		algorithm = crypto.SHA256
+	}
+	if !algorithm.Available() {
+		message := fmt.Sprintf("Hash algorithm %d is not linked into the binary", algorithm)
+		opts.logger().Warningf(message)
+		return "", errors.New(message)
+	}
+
+	text, err := opts.readFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	text, err = opts.forFile(filePath).unwrapText(text)
+	if err != nil {
+		return "", err
+	}
+
+	hash := algorithm.New()
+	hash.Write([]byte(text))
+	actual := hex.EncodeToString(hash.Sum(nil))
+
+	if !strings.EqualFold(actual, expectedHash) {
+		err := fmt.Errorf("%w: %s unwraps to %s, expected %s", ErrHashMismatch, filePath, actual, expectedHash)
+		opts.logger().Warningf(err.Error())
+		return text, err
+	}
+	return text, nil
+}
@@ -0,0 +1,41 @@
+package lines
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUnwrapVerify(t *testing.T) {
+	filePath := writeTemp(t, "page.tmpl", "a \\\nb\n")
+	sum256 := sha256.Sum256([]byte("a b\n\n"))
+	sum512 := sha512.Sum512([]byte("a b\n\n"))
+
+	tests := []struct {
+		name     string
+		expected string
+		hash     crypto.Hash
+		mismatch bool
+	}{
+		{"matching hash", hex.EncodeToString(sum256[:]), 0, false},
+		{"upper case hash", strings.ToUpper(hex.EncodeToString(sum256[:])), 0, false},
+		{"mismatching hash", hex.EncodeToString(sum512[:32]), 0, true},
+		{"SHA-512", hex.EncodeToString(sum512[:]), crypto.SHA512, false},
+		{"SHA-512 mismatch", hex.EncodeToString(sum256[:]), crypto.SHA512, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text, err := UnwrapVerifyWithOptions(filePath, test.expected, Options{Hash: test.hash})
+			if errors.Is(err, ErrHashMismatch) != test.mismatch || (err != nil && !test.mismatch) {
+				t.Errorf("got %v, want a mismatch: %t", err, test.mismatch)
+			}
+			if text != "a b\n\n" {
+				t.Errorf("unwrapped to %q", text)
+			}
+		})
+	}
+}
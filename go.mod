@@ -1,5 +1,9 @@
 module github.com/velmascooby/tools
 
-go 1.14
+go 1.16
 
-require github.com/google/logger v1.1.0
+require (
+	github.com/google/logger v1.1.0
+	golang.org/x/sys v0.0.0-20190215142949-d0b11bdaac8a
+	golang.org/x/text v0.3.3
+)